@@ -10,6 +10,6 @@ import (
 func main() {
 	if err := cli.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }
@@ -0,0 +1,215 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/logger"
+	"github.com/kacperzielinskidev/getctx/internal/tokens"
+)
+
+// benchFiles is the size of the synthetic tree the benchmarks work on.
+const benchFiles = 50000
+
+// The stages of a build on the synthetic tree, each with one worker and with
+// more. Run them with "make bench"; the tree is generated on the first run
+// and reused after that.
+
+func BenchmarkDiscover(b *testing.B) {
+	root := benchTree(b)
+	benchJobs(b, func(b *testing.B, cb *ContextBuilder) {
+		pathFilter := filter.New(cb.fsys, cb.config)
+		for b.Loop() {
+			if _, _, err := fs.DiscoverFilesParallel(context.Background(), cb.fsys, []string{root}, pathFilter, cb.jobs()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkFilterTextFiles(b *testing.B) {
+	root := benchTree(b)
+	benchJobs(b, func(b *testing.B, cb *ContextBuilder) {
+		files := benchDiscover(b, cb, root)
+		for b.Loop() {
+			cb.filterTextFiles(files, true)
+		}
+	})
+}
+
+func BenchmarkWriteContextFile(b *testing.B) {
+	root := benchTree(b)
+	benchJobs(b, func(b *testing.B, cb *ContextBuilder) {
+		files, _ := cb.filterTextFiles(benchDiscover(b, cb, root), true)
+		formatter, err := newFormatter(cb.config, cb.fsys)
+		if err != nil {
+			b.Fatal(err)
+		}
+		stats := cb.collectStats(files)
+		for b.Loop() {
+			if _, _, err := cb.writeContextFile(cb.config.OutputFilename, files, formatter, stats, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkBuild(b *testing.B) {
+	root := benchTree(b)
+	benchJobs(b, func(b *testing.B, cb *ContextBuilder) {
+		for b.Loop() {
+			if _, err := cb.Build([]string{root}, cb.config.OutputFilename); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkBuildSlowDisk is BenchmarkBuild on a file system where every
+// directory read and file open waits, as on a cold cache or a network mount.
+// The waits of several workers overlap even on a single CPU.
+func BenchmarkBuildSlowDisk(b *testing.B) {
+	root := benchTree(b)
+	benchJobs(b, func(b *testing.B, cb *ContextBuilder) {
+		cb.fsys = slowFileSystem{FileSystem: cb.fsys, delay: 100 * time.Microsecond}
+		for b.Loop() {
+			if _, err := cb.Build([]string{root}, cb.config.OutputFilename); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+type slowFileSystem struct {
+	fs.FileSystem
+	delay time.Duration
+}
+
+func (s slowFileSystem) ReadDir(name string) ([]iofs.DirEntry, error) {
+	time.Sleep(s.delay)
+	return s.FileSystem.ReadDir(name)
+}
+
+func (s slowFileSystem) Open(name string) (iofs.File, error) {
+	time.Sleep(s.delay)
+	return s.FileSystem.Open(name)
+}
+
+// benchJobs runs fn once per number of workers, each time with a builder
+// for the synthetic tree that writes outside of it.
+func benchJobs(b *testing.B, fn func(b *testing.B, cb *ContextBuilder)) {
+	jobs := []int{1, 4, runtime.NumCPU()}
+	slices.Sort(jobs)
+	for _, n := range slices.Compact(jobs) {
+		b.Run("jobs="+strconv.Itoa(n), func(b *testing.B) {
+			cfg := config.NewConfig()
+			cfg.Exclude.Base = filepath.Dir(benchTree(b))
+			cfg.OutputFilename = filepath.Join(b.TempDir(), config.DefaultOutputFilename)
+			cfg.Jobs = n
+			cb := NewContextBuilder(logger.New(io.Discard, logger.LevelInfo), fs.NewOSFileSystem(), cfg, tokens.NewHeuristicCounter())
+			cb.timings = newFileTimings()
+			fn(b, cb)
+		})
+	}
+}
+
+func benchDiscover(b *testing.B, cb *ContextBuilder, root string) []string {
+	b.Helper()
+	files, _, err := fs.DiscoverFiles(cb.fsys, []string{root}, filter.New(cb.fsys, cb.config))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return files
+}
+
+// benchTree returns the synthetic tree, generating it in the temporary
+// directory if an earlier run has not.
+func benchTree(b *testing.B) string {
+	b.Helper()
+	root := filepath.Join(os.TempDir(), "getctx-bench", strconv.Itoa(benchFiles))
+	if _, err := os.Stat(root); err == nil {
+		return root
+	}
+	b.Logf("generating %d files in %s", benchFiles, root)
+	tmp := root + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		b.Fatal(err)
+	}
+	if err := generateTree(tmp, benchFiles); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.Rename(tmp, root); err != nil {
+		b.Fatal(err)
+	}
+	return root
+}
+
+// generateTree writes a tree that looks like a large monorepo: packages of
+// nested directories holding mostly small source files, with a few large
+// ones and a few binaries in between. The same count gives the same tree.
+func generateTree(root string, files int) error {
+	rng := rand.New(rand.NewSource(int64(files)))
+	const filesPerDir = 50
+	for i := range files {
+		dir := filepath.Join(root,
+			fmt.Sprintf("service%02d", i/(filesPerDir*20)),
+			fmt.Sprintf("pkg%02d", i/filesPerDir%20))
+		if i%filesPerDir == 0 {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+
+		var name string
+		var content []byte
+		switch {
+		case i%100 == 99:
+			name, content = fmt.Sprintf("asset%05d.dat", i), randomBytes(rng, 16*1024)
+		case i%250 == 0:
+			name, content = fmt.Sprintf("generated%05d.go", i), randomSource(rng, 512*1024)
+		default:
+			name, content = fmt.Sprintf("file%05d.go", i), randomSource(rng, 1024+rng.Intn(8*1024))
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var benchWords = strings.Fields("func return if else for range var const type struct interface map string int error nil err ctx value result config path file")
+
+func randomSource(rng *rand.Rand, size int) []byte {
+	var b strings.Builder
+	b.WriteString("package bench\n\n")
+	for b.Len() < size {
+		b.WriteByte('\t')
+		for n := 3 + rng.Intn(8); n > 0; n-- {
+			b.WriteString(benchWords[rng.Intn(len(benchWords))])
+			b.WriteByte(' ')
+		}
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+func randomBytes(rng *rand.Rand, size int) []byte {
+	data := make([]byte, size)
+	rng.Read(data)
+	data[0] = 0
+	return data
+}
@@ -0,0 +1,118 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/tokens"
+)
+
+const (
+	BudgetFail = "fail"
+	BudgetDrop = "drop"
+)
+
+var ErrTokenBudgetExceeded = errors.New("token budget exceeded")
+
+// countTokens streams every file once to learn its token count before
+// anything is written, several files at once. Files that cannot be read are
+// left out; writing them will fail later and be reported there.
+func (cb *ContextBuilder) countTokens(files []string) map[string]int {
+	counts := make([]int, len(files))
+	errs := make([]error, len(files))
+	forEach(len(files), cb.jobs(), func(i int) {
+		cb.timings.track(files[i], func() {
+			counts[i], errs[i] = cb.countFileTokens(files[i])
+		})
+	})
+
+	tokenCounts := make(map[string]int, len(files))
+	for i, path := range files {
+		if errs[i] == nil {
+			tokenCounts[path] = counts[i]
+		}
+	}
+	return tokenCounts
+}
+
+func (cb *ContextBuilder) countFileTokens(path string) (int, error) {
+	file, err := cb.fsys.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return tokens.CountReader(cb.counter, file)
+}
+
+// applyTokenBudget enforces config.MaxTokens. With the "fail" strategy an
+// oversized selection is an error; with "drop" files are kept in priority
+// order (explicitly selected before discovered, shallow before deep, small
+// before large) for as long as they fit, and the rest is returned as dropped.
+func (cb *ContextBuilder) applyTokenBudget(files []string, counts map[string]int, selectedPaths []string) ([]string, []FileResult, error) {
+	maxTokens := cb.config.MaxTokens
+	total := 0
+	for _, path := range files {
+		total += counts[path]
+	}
+	if total <= maxTokens {
+		return files, nil, nil
+	}
+
+	if cb.config.TokenBudget != BudgetDrop {
+		return nil, nil, fmt.Errorf("%w: the selection has %d tokens, the limit is %d", ErrTokenBudgetExceeded, total, maxTokens)
+	}
+
+	explicit := make(map[string]struct{}, len(selectedPaths))
+	for _, path := range selectedPaths {
+		explicit[filepath.Clean(path)] = struct{}{}
+	}
+	priority := func(path string) int {
+		if _, ok := explicit[filepath.Clean(path)]; ok {
+			return 0
+		}
+		return 1
+	}
+	depth := func(path string) int {
+		return strings.Count(filepath.Clean(path), string(filepath.Separator))
+	}
+
+	candidates := append([]string(nil), files...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if pa, pb := priority(a), priority(b); pa != pb {
+			return pa < pb
+		}
+		if da, db := depth(a), depth(b); da != db {
+			return da < db
+		}
+		if counts[a] != counts[b] {
+			return counts[a] < counts[b]
+		}
+		return a < b
+	})
+
+	kept := make(map[string]struct{}, len(candidates))
+	used := 0
+	var dropped []FileResult
+	for _, path := range candidates {
+		if used+counts[path] > maxTokens {
+			dropped = append(dropped, FileResult{Path: path, Tokens: counts[path]})
+			continue
+		}
+		used += counts[path]
+		kept[path] = struct{}{}
+	}
+
+	var keptFiles []string
+	for _, path := range files {
+		if _, ok := kept[path]; ok {
+			keptFiles = append(keptFiles, path)
+		}
+	}
+	sort.Slice(dropped, func(i, j int) bool { return dropped[i].Path < dropped[j].Path })
+
+	return keptFiles, dropped, nil
+}
@@ -0,0 +1,148 @@
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	iofs "io/fs"
+
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/tokens"
+)
+
+// copyBufferSize is the read buffer of a file being streamed into the output.
+const copyBufferSize = 32 * 1024
+
+// contentSource is a file opened once for the build: its content type is
+// sniffed from the read buffer and the same reader is then copied into the
+// output, so no file larger than prefetchLimit is ever held in memory as a
+// whole.
+type contentSource struct {
+	file   io.ReadCloser
+	reader *bufio.Reader
+	head   []byte
+	// err is the first error reading the file, as opposed to writing it.
+	err error
+}
+
+// openContent opens path and sniffs its content type without consuming it.
+func openContent(fsys fs.FileSystem, path string) (*contentSource, string, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	source := &contentSource{file: file, reader: bufio.NewReaderSize(file, copyBufferSize)}
+	contentType, head, err := fs.SniffContentType(source.reader)
+	if err != nil {
+		file.Close()
+		return nil, "", err
+	}
+	source.head = bytes.Clone(head)
+	return source, contentType, nil
+}
+
+// readAhead reads a file of up to limit bytes into memory and closes it, so
+// it can be written later without touching the disk. Larger files, and files
+// whose size is unknown, are left to be streamed and nil is returned.
+func (s *contentSource) readAhead(limit int64) ([]byte, error) {
+	statter, ok := s.file.(interface{ Stat() (iofs.FileInfo, error) })
+	if !ok {
+		return nil, nil
+	}
+	info, err := statter.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() > limit {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(s)
+	if err != nil {
+		return nil, err
+	}
+	s.file.Close()
+	s.file = memoryFile{bytes.NewReader(data)}
+	s.reader.Reset(s.file)
+	return data, nil
+}
+
+func (s *contentSource) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	if err != nil && err != io.EOF && s.err == nil {
+		s.err = err
+	}
+	return n, err
+}
+
+// scan feeds the whole content to fn and rewinds, for formats that must know
+// something about the file before they write it. A file that can not seek is
+// read into memory instead.
+func (s *contentSource) scan(fn func(chunk []byte)) error {
+	seeker, ok := s.file.(io.Seeker)
+	if !ok {
+		data, err := io.ReadAll(s)
+		if err != nil {
+			return err
+		}
+		fn(data)
+		s.reader = bufio.NewReader(bytes.NewReader(data))
+		return nil
+	}
+
+	buf := make([]byte, copyBufferSize)
+	for {
+		n, err := s.Read(buf)
+		fn(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		s.err = err
+		return err
+	}
+	s.reader.Reset(s.file)
+	return nil
+}
+
+func (s *contentSource) Close() error {
+	return s.file.Close()
+}
+
+// memoryFile is the content of a file read ahead.
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
+
+// contentMeter sees the content of a file on its way into the output and
+// measures its size and, unless they are already known, its tokens.
+type contentMeter struct {
+	bytes  int64
+	tokens *tokens.Stream
+}
+
+func (m *contentMeter) Write(p []byte) (int, error) {
+	m.bytes += int64(len(p))
+	if m.tokens != nil {
+		m.tokens.Write(p)
+	}
+	return len(p), nil
+}
+
+// countingWriter counts the bytes written to the output.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,40 +1,67 @@
 package build
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	iofs "io/fs"
 	"sort"
+	"time"
 
 	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/filter"
 	"github.com/kacperzielinskidev/getctx/internal/fs"
 	"github.com/kacperzielinskidev/getctx/internal/logger"
-)
-
-const (
-	fileHeaderFormat = "--- START OF FILE: %s ---\n"
-	fileFooterFormat = "\n--- END OF FILE: %s ---\n\n"
+	"github.com/kacperzielinskidev/getctx/internal/tokens"
 )
 
 type ContextBuilder struct {
-	log    *logger.Logger
-	fsys   fs.FileSystem
-	config *config.Config
+	log     *logger.Logger
+	fsys    fs.FileSystem
+	config  *config.Config
+	counter tokens.Counter
+	// timings collects the time spent per file during a build.
+	timings *fileTimings
+}
+
+type FileResult struct {
+	Path string
+	// Size is the length of the file's content, Written the bytes it took
+	// in the output, formatting included.
+	Size    int64
+	Written int64
+	Tokens  int
 }
 
 type BuildResult struct {
 	FilesProcessed int
 	FilesSkipped   int
-	PathsWithErr   []string
+	// Failures lists every file that was left out, with the reason.
+	Failures []FileFailure
+	// Warnings are problems that concern no single file.
+	Warnings     []string
+	Tokenizer    string
+	TotalTokens  int
+	Files        []FileResult
+	DroppedFiles []FileResult
+	// Report details every path the build looked at.
+	Report *Report
 }
 
-func NewContextBuilder(log *logger.Logger, fsys fs.FileSystem, cfg *config.Config) *ContextBuilder {
+func NewContextBuilder(log *logger.Logger, fsys fs.FileSystem, cfg *config.Config, counter tokens.Counter) *ContextBuilder {
 	return &ContextBuilder{
-		log:    log,
-		fsys:   fsys,
-		config: cfg,
+		log:     log,
+		fsys:    fsys,
+		config:  cfg,
+		counter: counter,
+		timings: newFileTimings(),
 	}
 }
 
+// Build writes the context of the selected paths to outputFilename. Once
+// discovery has started, a failed build still returns what it found, with its
+// report, along with the error.
 func (cb *ContextBuilder) Build(selectedPaths []string, outputFilename string) (*BuildResult, error) {
 	if len(selectedPaths) == 0 {
 		cb.log.Info("BuildContext", "No items selected by user, exiting.")
@@ -46,31 +73,80 @@ func (cb *ContextBuilder) Build(selectedPaths []string, outputFilename string) (
 		"selected_paths":      selectedPaths,
 	})
 
-	allFiles, warnings, err := fs.DiscoverFiles(cb.fsys, selectedPaths, cb.config.ExcludedNames)
+	formatter, err := newFormatter(cb.config, cb.fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	cb.timings = newFileTimings()
+	pathFilter := filter.New(cb.fsys, cb.config)
+	result := &BuildResult{Tokenizer: cb.counter.Name()}
+	result.Failures = cb.excludedSelections(selectedPaths, pathFilter)
+
+	recorder := &recordingFilter{Filter: pathFilter}
+	allFiles, discoveryErrs, err := fs.DiscoverFilesParallel(context.Background(), cb.fsys, selectedPaths, recorder, cb.jobs())
 	if err != nil {
 		cb.log.Error("BuildContext.DiscoverFiles", err)
-		return nil, fmt.Errorf("error discovering files: %w", err)
+		result.Report = cb.newReport(result, recorder.excluded, outputFilename, started)
+		return result, fmt.Errorf("error discovering files: %w", err)
+	}
+	for _, discoveryErr := range discoveryErrs {
+		result.Failures = append(result.Failures, readFailure("", discoveryErr))
 	}
 
-	textFiles := cb.filterTextFiles(allFiles)
+	textFiles, skipped := cb.filterTextFiles(allFiles, cb.needsTextFilesUpfront())
+	result.FilesSkipped = len(allFiles) - len(textFiles)
+	result.Failures = append(result.Failures, skipped...)
 
-	result := &BuildResult{
-		FilesProcessed: len(textFiles),
-		FilesSkipped:   len(allFiles) - len(textFiles),
-		PathsWithErr:   warnings,
+	var tokenCounts map[string]int
+	if cb.config.MaxTokens > 0 {
+		tokenCounts = cb.countTokens(textFiles)
+		kept, dropped, err := cb.applyTokenBudget(textFiles, tokenCounts, selectedPaths)
+		if err != nil {
+			cb.log.Error("BuildContext.applyTokenBudget", err)
+			// Nothing is written, so the report lists every file as dropped.
+			for _, path := range textFiles {
+				result.DroppedFiles = append(result.DroppedFiles, FileResult{Path: path, Tokens: tokenCounts[path]})
+			}
+			result.Report = cb.newReport(result, recorder.excluded, outputFilename, started)
+			return result, err
+		}
+		textFiles, result.DroppedFiles = kept, dropped
 	}
+	result.FilesProcessed = len(textFiles)
 
 	if len(textFiles) == 0 {
 		cb.log.Info("BuildContext", "No text files found to process.")
+		result.Report = cb.newReport(result, recorder.excluded, outputFilename, started)
 		return result, nil
 	}
 
 	cb.log.Info("BuildContext", map[string]any{
 		"files_to_process_count": len(textFiles),
 		"output_filename":        outputFilename,
+		"format":                 cb.config.Format,
 	})
 
-	err = cb.writeContextFile(outputFilename, textFiles)
+	stats := cb.collectStats(textFiles)
+	if cb.config.TreeMap {
+		tree, err := cb.buildTree(selectedPaths, cb.treeMarks(textFiles, skipped, result.DroppedFiles), pathFilter)
+		if err != nil {
+			cb.log.Warn("BuildContext.buildTree", err)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Could not build the project tree: %v", err))
+		}
+		stats.tree = tree
+	}
+
+	files, failures, err := cb.writeContextFile(outputFilename, textFiles, formatter, stats, tokenCounts)
+	result.Files = files
+	result.FilesProcessed = len(files)
+	result.FilesSkipped = len(allFiles) - len(files) - len(result.DroppedFiles)
+	result.Failures = append(result.Failures, failures...)
+	for _, file := range files {
+		result.TotalTokens += file.Tokens
+	}
+	result.Report = cb.newReport(result, recorder.excluded, outputFilename, started)
 	if err != nil {
 		return result, err
 	}
@@ -78,69 +154,220 @@ func (cb *ContextBuilder) Build(selectedPaths []string, outputFilename string) (
 	return result, nil
 }
 
-func (cb *ContextBuilder) filterTextFiles(files []string) []string {
-	var textFiles []string
-	for _, path := range files {
-		isText, err := fs.IsTextFile(cb.fsys, path)
+// excludedSelections reports the selected paths that the rules leave out.
+// Discovery skips them silently, like any other excluded path.
+func (cb *ContextBuilder) excludedSelections(selectedPaths []string, pathFilter *filter.Filter) []FileFailure {
+	var failures []FileFailure
+	for _, path := range selectedPaths {
+		info, err := cb.fsys.Stat(path)
 		if err != nil {
-			cb.log.Warn("filterTextFiles", map[string]any{
-				"message": "Could not check file type",
-				"path":    path,
-				"error":   err.Error(),
-			})
 			continue
 		}
-		if isText {
-			textFiles = append(textFiles, path)
+		decision := pathFilter.Decide(path, iofs.FileInfoToDirEntry(info))
+		if decision.Excluded() {
+			failures = append(failures, FileFailure{Path: path, Reason: SkippedExcluded, Detail: describeDecision(decision)})
 		}
 	}
-	return textFiles
+	return failures
 }
 
-func (cb *ContextBuilder) writeContextFile(outputFilename string, files []string) error {
-	outputFile, err := cb.fsys.Create(outputFilename)
-	if err != nil {
-		cb.log.Error("writeContextFile.Create", err)
-		return fmt.Errorf("failed to create output file %s: %w", outputFilename, err)
+// readFailure reports err for path, or for the path of the *fs.PathError it
+// wraps, which already names it.
+func readFailure(path string, err error) FileFailure {
+	var pathErr *iofs.PathError
+	if errors.As(err, &pathErr) {
+		path, err = pathErr.Path, pathErr.Err
 	}
-	defer outputFile.Close()
+	return FileFailure{Path: path, Reason: ReadFailed, Detail: err.Error()}
+}
 
-	sort.Strings(files)
+// needsTextFilesUpfront reports whether the text files must be known before
+// the output is written: for the tree map, the token budget and the stats a
+// template receives. Otherwise each file is opened only once, when it is
+// written, and sniffed there.
+func (cb *ContextBuilder) needsTextFilesUpfront() bool {
+	return cb.config.TreeMap || cb.config.MaxTokens > 0 || cb.config.Format == FormatTemplate
+}
 
-	for _, path := range files {
-		if err := cb.appendFileToContext(outputFile, path); err != nil {
-			// Log the warning but continue processing other files.
-			cb.log.Warn("writeContextFile.append", map[string]any{
-				"message": "Failed to append file to context, skipping",
+// filterTextFiles keeps the files within the configured size limit and, with
+// sniff, only the text files among them. It also returns every file it left
+// out with the reason. The files are checked concurrently, but the results
+// keep their order.
+func (cb *ContextBuilder) filterTextFiles(files []string, sniff bool) ([]string, []FileFailure) {
+	outcomes := make([]*FileFailure, len(files))
+	forEach(len(files), cb.jobs(), func(i int) {
+		cb.timings.track(files[i], func() {
+			outcomes[i] = cb.checkFile(files[i], sniff)
+		})
+	})
+
+	var textFiles []string
+	var skipped []FileFailure
+	for i, failure := range outcomes {
+		if failure == nil {
+			textFiles = append(textFiles, files[i])
+		} else {
+			skipped = append(skipped, *failure)
+		}
+	}
+	return textFiles, skipped
+}
+
+// checkFile returns why path is left out, or nil if it is kept.
+func (cb *ContextBuilder) checkFile(path string, sniff bool) *FileFailure {
+	if cb.config.MaxFileSize > 0 {
+		info, err := cb.fsys.Stat(path)
+		if err == nil && info.Size() > cb.config.MaxFileSize {
+			cb.log.Info("filterTextFiles", map[string]any{
+				"message": "Skipping file larger than the size limit",
 				"path":    path,
-				"error":   err.Error(),
+				"size":    info.Size(),
 			})
+			return &FileFailure{
+				Path:   path,
+				Reason: SkippedTooLarge,
+				Detail: fmt.Sprintf("%d bytes, limit %d bytes", info.Size(), cb.config.MaxFileSize),
+			}
 		}
 	}
 
+	if !sniff {
+		return nil
+	}
+
+	isText, err := fs.IsTextFile(cb.fsys, path)
+	if err != nil {
+		cb.log.Warn("filterTextFiles", map[string]any{
+			"message": "Could not check file type",
+			"path":    path,
+			"error":   err.Error(),
+		})
+		failure := readFailure(path, err)
+		return &failure
+	}
+	if !isText {
+		return &FileFailure{Path: path, Reason: SkippedBinary}
+	}
 	return nil
 }
 
-func (cb *ContextBuilder) appendFileToContext(writer io.Writer, path string) error {
-	content, err := cb.fsys.ReadFile(path)
+// writeContextFile streams the files into the output, opening each once.
+// Workers open, sniff and read the files ahead while a single writer formats
+// them in order. A file that turns out to be binary or can not be read is
+// reported and left out; a failed read or write halfway through a file stops
+// the build and keeps the previous output. Without a single file written, no
+// output is created.
+func (cb *ContextBuilder) writeContextFile(outputFilename string, files []string, formatter formatter, stats *buildStats, tokenCounts map[string]int) ([]FileResult, []FileFailure, error) {
+	// The context is written next to the output file and only moved over it
+	// once complete, so a failed build keeps the previous context intact.
+	atomicFile, err := fs.CreateAtomic(cb.fsys, outputFilename)
 	if err != nil {
-		return fmt.Errorf("could not read file %s: %w", path, err)
+		cb.log.Error("writeContextFile.Create", err)
+		return nil, nil, fmt.Errorf("failed to create output file %s: %w", outputFilename, err)
 	}
+	defer atomicFile.Abort()
+	outputFile := &countingWriter{w: atomicFile}
+
+	sort.Strings(files)
 
-	// Write header
-	if _, err := fmt.Fprintf(writer, fileHeaderFormat, path); err != nil {
-		return fmt.Errorf("error writing header for file %s: %w", path, err)
+	paths := cb.newPathResolver(files)
+
+	if err := formatter.begin(outputFile, stats); err != nil {
+		return nil, nil, fmt.Errorf("failed to write to output file %s: %w", outputFilename, err)
 	}
 
-	// Write content
-	if _, err := writer.Write(content); err != nil {
-		return fmt.Errorf("error writing content for file %s: %w", path, err)
+	results := make([]FileResult, 0, len(files))
+	var failures []FileFailure
+
+	ahead := cb.prefetch(files, tokenCounts)
+	defer ahead.close()
+
+	for i, path := range files {
+		prepared := ahead.next(i)
+		// Files left out do not use up an index.
+		file := &fileEntry{
+			index:        len(results) + 1,
+			path:         path,
+			relativePath: paths.display(path),
+		}
+		var writeErr error
+		cb.timings.track(path, func() {
+			if prepared.err != nil {
+				cb.log.Warn("writeContextFile.prepareFile", map[string]any{
+					"message": "Failed to read file, skipping",
+					"path":    path,
+					"error":   prepared.err.Error(),
+				})
+				failures = append(failures, readFailure(path, prepared.err))
+				return
+			}
+			source := prepared.source
+			defer source.Close()
+			if !fs.IsText(prepared.contentType) {
+				failures = append(failures, FileFailure{Path: path, Reason: SkippedBinary})
+				return
+			}
+
+			meter := &contentMeter{}
+			tokenCount, counted := tokenCounts[path]
+			if prepared.counted {
+				tokenCount, counted = prepared.tokens, true
+			}
+			if !counted {
+				meter.tokens = tokens.NewStream(cb.counter)
+			}
+			file.head = source.head
+			file.source = source
+			file.content = io.TeeReader(source, meter)
+
+			written := outputFile.n
+			if err := formatter.writeFile(outputFile, file); err != nil {
+				cb.log.Error("writeContextFile.writeFile", err)
+				reason := WriteFailed
+				if source.err != nil {
+					reason = ReadFailed
+				}
+				failures = append(failures, FileFailure{Path: path, Reason: reason, Detail: err.Error()})
+				writeErr = fmt.Errorf("failed to write %s to output file %s: %w", path, outputFilename, err)
+				return
+			}
+
+			if !counted {
+				tokenCount = meter.tokens.Total()
+			}
+			results = append(results, FileResult{
+				Path:    path,
+				Size:    meter.bytes,
+				Written: outputFile.n - written,
+				Tokens:  tokenCount,
+			})
+		})
+		ahead.done()
+		if writeErr != nil {
+			return results, failures, writeErr
+		}
+	}
+	if len(results) == 0 {
+		return results, failures, nil
 	}
 
-	// Write footer
-	if _, err := fmt.Fprintf(writer, fileFooterFormat, path); err != nil {
-		return fmt.Errorf("error writing footer for file %s: %w", path, err)
+	if err := formatter.end(outputFile); err != nil {
+		return results, failures, fmt.Errorf("failed to write to output file %s: %w", outputFilename, err)
+	}
+	if err := atomicFile.Commit(); err != nil {
+		cb.log.Error("writeContextFile.Commit", err)
+		return results, failures, fmt.Errorf("failed to write to output file %s: %w", outputFilename, err)
 	}
 
-	return nil
+	return results, failures, nil
+}
+
+func (cb *ContextBuilder) collectStats(files []string) *buildStats {
+	stats := &buildStats{fileCount: len(files)}
+	for _, path := range files {
+		if info, err := cb.fsys.Stat(path); err == nil {
+			stats.totalSize += info.Size()
+		}
+	}
+	return stats
 }
@@ -0,0 +1,156 @@
+package build
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+)
+
+// ExplainStep is one stage of the pipeline applied to a single path.
+type ExplainStep struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Explanation describes how a build would treat one path.
+type Explanation struct {
+	Path     string
+	Included bool
+	Steps    []ExplainStep
+}
+
+// Explain runs the stages of Build on a single path, given explicitly as it
+// would be to build, and records the outcome of each one: the exclusion
+// rules for its parent directories and itself, the size limit, the content
+// type, reading it and the token budget. Later stages still run after a
+// failed one where that is possible, so every reason a file would be left
+// out shows up at once; a file that is not text is not read, as in Build.
+func (cb *ContextBuilder) Explain(path string) *Explanation {
+	e := &Explanation{Path: path}
+	step := func(name string, passed bool, format string, args ...any) {
+		e.Steps = append(e.Steps, ExplainStep{Name: name, Passed: passed, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	absPath, err := cb.fsys.Abs(path)
+	if err != nil {
+		step("path", false, "could not resolve the path: %v", err)
+		return e
+	}
+	info, err := cb.fsys.Stat(absPath)
+	if err != nil {
+		step("path", false, "could not stat the file: %v", err)
+		return e
+	}
+	kind := "file"
+	if info.IsDir() {
+		kind = "directory"
+	}
+	step("path", true, "%s, %s", kind, absPath)
+
+	pathFilter := filter.New(cb.fsys, cb.config)
+	cb.explainParents(pathFilter, absPath, step)
+
+	decision := pathFilter.Decide(absPath, iofs.FileInfoToDirEntry(info))
+	step("rules", !decision.Excluded(), "%s", describeDecision(decision))
+
+	if info.IsDir() {
+		step("directory", true, "building a directory includes the files below it that pass these checks")
+		e.Included = allPassed(e.Steps)
+		return e
+	}
+
+	if cb.config.MaxFileSize > 0 {
+		step("size", info.Size() <= cb.config.MaxFileSize, "%d bytes, limit %d bytes", info.Size(), cb.config.MaxFileSize)
+	} else {
+		step("size", true, "%d bytes, no limit", info.Size())
+	}
+
+	contentType, err := fs.DetectContentType(cb.fsys, absPath)
+	if err != nil {
+		step("content type", false, "could not read the start of the file: %v", err)
+		return e
+	}
+	step("content type", fs.IsText(contentType), "%s (only text/* is included)", contentType)
+	if !fs.IsText(contentType) {
+		return e
+	}
+
+	tokens, err := cb.countFileTokens(absPath)
+	if err != nil {
+		step("read", false, "%v", err)
+		e.Included = allPassed(e.Steps)
+		return e
+	}
+	step("read", true, "%d tokens (%s)", tokens, cb.counter.Name())
+
+	if cb.config.MaxTokens > 0 {
+		detail := "%d of %d tokens on its own"
+		if cb.config.TokenBudget == BudgetDrop {
+			detail += "; it may still be dropped to make room for higher-priority files"
+		}
+		step("token budget", tokens <= cb.config.MaxTokens, detail, tokens, cb.config.MaxTokens)
+	}
+
+	e.Included = allPassed(e.Steps)
+	return e
+}
+
+// explainParents checks the directories between the project root and the
+// path. A path given explicitly is built even below an excluded directory,
+// so such a directory is reported without failing the step; it only matters
+// when a directory above it is selected instead.
+func (cb *ContextBuilder) explainParents(pathFilter *filter.Filter, absPath string, step func(string, bool, string, ...any)) {
+	base := cb.config.Exclude.Base
+	if base == "" || !isWithin(base, filepath.Dir(absPath)) {
+		return
+	}
+
+	relDir, err := filepath.Rel(base, filepath.Dir(absPath))
+	if err != nil || relDir == "." {
+		return
+	}
+
+	dir := base
+	for _, component := range strings.Split(relDir, string(filepath.Separator)) {
+		dir = filepath.Join(dir, component)
+		info, err := cb.fsys.Stat(dir)
+		if err != nil {
+			continue
+		}
+		decision := pathFilter.Decide(dir, iofs.FileInfoToDirEntry(info))
+		if decision.Excluded() {
+			step("parent directories", true, "%s/ is excluded (%s), but a path given explicitly is included anyway; selecting a directory above it would skip it", dir, describeDecision(decision))
+			return
+		}
+	}
+	step("parent directories", true, "no rule excludes the directories between %s and the file", base)
+}
+
+func describeDecision(decision filter.Decision) string {
+	switch {
+	case decision.Reason == filter.OutputFile:
+		return "it is a file the run writes (the output, the report or an exported list), which is never read back in"
+	case decision.Rule == nil && decision.Reason == filter.NotIncluded:
+		return "not matched by any include pattern of a .getctxinclude file or the profile"
+	case decision.Rule == nil:
+		return "no rule matches"
+	case !decision.Excluded():
+		return fmt.Sprintf("re-included by %s", decision.Rule)
+	default:
+		return fmt.Sprintf("%s (%s)", decision.Reason, decision.Rule)
+	}
+}
+
+func allPassed(steps []ExplainStep) bool {
+	for _, step := range steps {
+		if !step.Passed {
+			return false
+		}
+	}
+	return true
+}
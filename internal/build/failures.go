@@ -0,0 +1,80 @@
+package build
+
+import "fmt"
+
+// FailureReason says why a file did not make it into the context.
+type FailureReason int
+
+const (
+	// ReadFailed means the file could not be stat'ed, listed or read.
+	ReadFailed FailureReason = iota
+	// WriteFailed means writing the file to the output failed. The output is
+	// left as it was.
+	WriteFailed
+	// SkippedBinary means the file is not text.
+	SkippedBinary
+	// SkippedTooLarge means the file exceeds the size limit.
+	SkippedTooLarge
+	// SkippedExcluded means a path that was selected explicitly is excluded
+	// by a rule. Excluded files inside a selected directory are not reported.
+	SkippedExcluded
+)
+
+func (r FailureReason) String() string {
+	switch r {
+	case ReadFailed:
+		return "read error"
+	case WriteFailed:
+		return "write error"
+	case SkippedBinary:
+		return "binary"
+	case SkippedTooLarge:
+		return "too large"
+	case SkippedExcluded:
+		return "excluded"
+	default:
+		return "unknown"
+	}
+}
+
+// FileFailure is a file or selected path that was left out of the context.
+type FileFailure struct {
+	Path   string
+	Reason FailureReason
+	Detail string
+}
+
+// Failed reports whether the file was left out by an error rather than by a
+// rule, which makes the build partial.
+func (f FileFailure) Failed() bool {
+	return f.Reason == ReadFailed || f.Reason == WriteFailed
+}
+
+func (f FileFailure) String() string {
+	if f.Detail == "" {
+		return fmt.Sprintf("%s (%s)", f.Path, f.Reason)
+	}
+	return fmt.Sprintf("%s (%s: %s)", f.Path, f.Reason, f.Detail)
+}
+
+// Errors returns the failures that make the build partial.
+func (r *BuildResult) Errors() []FileFailure {
+	var errs []FileFailure
+	for _, failure := range r.Failures {
+		if failure.Failed() {
+			errs = append(errs, failure)
+		}
+	}
+	return errs
+}
+
+// Skipped returns the files that were left out on purpose.
+func (r *BuildResult) Skipped() []FileFailure {
+	var skipped []FileFailure
+	for _, failure := range r.Failures {
+		if !failure.Failed() {
+			skipped = append(skipped, failure)
+		}
+	}
+	return skipped
+}
@@ -0,0 +1,124 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+)
+
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+	FormatXML      = "xml"
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+	FormatTemplate = "template"
+)
+
+var supportedFormats = []string{FormatPlain, FormatMarkdown, FormatXML, FormatJSON, FormatJSONL, FormatTemplate}
+
+const (
+	fileHeaderFormat = "--- START OF FILE: %s ---\n"
+	fileFooterFormat = "\n--- END OF FILE: %s ---\n\n"
+	treeHeader       = "--- PROJECT TREE ---\n"
+	treeFooter       = "--- END OF PROJECT TREE ---\n\n"
+)
+
+type fileEntry struct {
+	index        int
+	path         string
+	relativePath string
+	// head is the start of the file, enough to detect its language.
+	head []byte
+	// content streams the file from the start.
+	content io.Reader
+	source  *contentSource
+}
+
+// scan reads the whole file ahead of content, see contentSource.scan. It
+// must be called before content is read.
+func (f *fileEntry) scan(fn func(chunk []byte)) error {
+	return f.source.scan(fn)
+}
+
+// buildStats describes the whole selection and is known before the first
+// file is written.
+type buildStats struct {
+	fileCount int
+	totalSize int64
+	tree      string
+}
+
+// formatter decides how the selected files are laid out in the output file.
+type formatter interface {
+	begin(w io.Writer, stats *buildStats) error
+	writeFile(w io.Writer, file *fileEntry) error
+	end(w io.Writer) error
+}
+
+func SupportedFormats() []string {
+	return append([]string(nil), supportedFormats...)
+}
+
+func ValidateFormat(name string) error {
+	if !slices.Contains(supportedFormats, name) {
+		return fmt.Errorf("unknown output format %q (supported: %s)", name, strings.Join(supportedFormats, ", "))
+	}
+	return nil
+}
+
+func newFormatter(cfg *config.Config, fsys fs.FileSystem) (formatter, error) {
+	switch cfg.Format {
+	case FormatPlain:
+		return plainFormatter{}, nil
+	case FormatMarkdown:
+		return markdownFormatter{}, nil
+	case FormatXML:
+		return xmlFormatter{wrap: cfg.XMLDocumentsWrapper}, nil
+	case FormatJSON:
+		return &jsonFormatter{}, nil
+	case FormatJSONL:
+		return &jsonFormatter{lines: true}, nil
+	case FormatTemplate:
+		if cfg.Template == "" {
+			return nil, fmt.Errorf("the %s format requires a template file", FormatTemplate)
+		}
+		return newTemplateFormatter(fsys, cfg.Template)
+	default:
+		return nil, ValidateFormat(cfg.Format)
+	}
+}
+
+type plainFormatter struct{}
+
+func (plainFormatter) begin(w io.Writer, stats *buildStats) error {
+	if stats.tree == "" {
+		return nil
+	}
+	_, err := io.WriteString(w, treeHeader+stats.tree+treeFooter)
+	return err
+}
+
+func (plainFormatter) writeFile(w io.Writer, file *fileEntry) error {
+	if _, err := fmt.Fprintf(w, fileHeaderFormat, file.relativePath); err != nil {
+		return fmt.Errorf("error writing header for file %s: %w", file.path, err)
+	}
+
+	if _, err := io.Copy(w, file.content); err != nil {
+		return fmt.Errorf("error writing content for file %s: %w", file.path, err)
+	}
+
+	if _, err := fmt.Fprintf(w, fileFooterFormat, file.relativePath); err != nil {
+		return fmt.Errorf("error writing footer for file %s: %w", file.path, err)
+	}
+
+	return nil
+}
+
+func (plainFormatter) end(w io.Writer) error {
+	return nil
+}
@@ -0,0 +1,178 @@
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// fileRecord holds the fields of a record that precede its content, which
+// is streamed after them as the last field, "content".
+type fileRecord struct {
+	Path         string `json:"path"`
+	RelativePath string `json:"relative_path"`
+	Size         int64  `json:"size"`
+	Lines        int    `json:"lines"`
+	Language     string `json:"language,omitempty"`
+	SHA256       string `json:"sha256"`
+}
+
+// jsonFormatter writes one record per file. Records are written as soon as a
+// file is processed and the content is streamed into them, so neither the
+// output nor a file is ever held in memory; in array mode the surrounding
+// brackets are written by begin and end.
+type jsonFormatter struct {
+	lines   bool
+	written int
+}
+
+func (f *jsonFormatter) begin(w io.Writer, stats *buildStats) error {
+	if f.lines {
+		return nil
+	}
+	_, err := io.WriteString(w, "[\n")
+	return err
+}
+
+// writeFile scans the file once for its size, line count and hash, then
+// writes the record with the content streamed into it.
+func (f *jsonFormatter) writeFile(w io.Writer, file *fileEntry) error {
+	hash := sha256.New()
+	var lines lineCounter
+	err := file.scan(func(chunk []byte) {
+		hash.Write(chunk)
+		lines.write(chunk)
+	})
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", file.path, err)
+	}
+
+	entry := fileRecord{
+		Path:         file.path,
+		RelativePath: file.relativePath,
+		Size:         lines.size,
+		Lines:        lines.count(),
+		Language:     detectLanguage(file.path, file.head),
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(entry); err != nil {
+		return fmt.Errorf("error encoding record for file %s: %w", file.path, err)
+	}
+
+	// The record stays open for the content. In array mode the separator
+	// goes in front of the record, in jsonl a newline terminates it.
+	record := bytes.TrimSuffix(data.Bytes(), []byte("}\n"))
+	if !f.lines && f.written > 0 {
+		record = append([]byte(",\n"), record...)
+	}
+	record = append(record, `,"content":`...)
+	closing := "}"
+	if f.lines {
+		closing += "\n"
+	}
+
+	if _, err := w.Write(record); err != nil {
+		return fmt.Errorf("error writing record for file %s: %w", file.path, err)
+	}
+	if err := writeJSONString(w, file.content); err != nil {
+		return fmt.Errorf("error writing content for file %s: %w", file.path, err)
+	}
+	if _, err := io.WriteString(w, closing); err != nil {
+		return fmt.Errorf("error writing record for file %s: %w", file.path, err)
+	}
+	f.written++
+
+	return nil
+}
+
+func (f *jsonFormatter) end(w io.Writer) error {
+	if f.lines {
+		return nil
+	}
+	closing := "]\n"
+	if f.written > 0 {
+		closing = "\n]\n"
+	}
+	_, err := io.WriteString(w, closing)
+	return err
+}
+
+// lineCounter counts the lines of content that arrives in chunks; a last
+// line without a trailing newline counts too.
+type lineCounter struct {
+	size     int64
+	newlines int
+	last     byte
+}
+
+func (c *lineCounter) write(chunk []byte) {
+	if len(chunk) == 0 {
+		return
+	}
+	c.size += int64(len(chunk))
+	c.newlines += bytes.Count(chunk, []byte{'\n'})
+	c.last = chunk[len(chunk)-1]
+}
+
+func (c *lineCounter) count() int {
+	if c.size > 0 && c.last != '\n' {
+		return c.newlines + 1
+	}
+	return c.newlines
+}
+
+// writeJSONString streams r as a JSON string, escaped the way encoding/json
+// does with HTML escaping turned off.
+func writeJSONString(w io.Writer, r io.Reader) error {
+	const hexDigits = "0123456789abcdef"
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+	out.WriteByte('"')
+	for {
+		char, size, err := in.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case char == '"' || char == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(char)
+		case char == '\b':
+			out.WriteString(`\b`)
+		case char == '\f':
+			out.WriteString(`\f`)
+		case char == '\n':
+			out.WriteString(`\n`)
+		case char == '\r':
+			out.WriteString(`\r`)
+		case char == '\t':
+			out.WriteString(`\t`)
+		case char < 0x20:
+			out.WriteString(`\u00`)
+			out.WriteByte(hexDigits[char>>4])
+			out.WriteByte(hexDigits[char&0xF])
+		case char == utf8.RuneError && size == 1:
+			out.WriteString(`\ufffd`)
+		case char == '\u2028' || char == '\u2029':
+			out.WriteString(`\u202`)
+			out.WriteByte(hexDigits[char&0xF])
+		default:
+			out.WriteRune(char)
+		}
+	}
+	out.WriteByte('"')
+	return out.Flush()
+}
@@ -0,0 +1,91 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const minFenceLength = 3
+
+type markdownFormatter struct{}
+
+func (markdownFormatter) begin(w io.Writer, stats *buildStats) error {
+	if stats.tree == "" {
+		return nil
+	}
+	fence := strings.Repeat("`", fenceLength([]byte(stats.tree)))
+	_, err := fmt.Fprintf(w, "## Project tree\n\n%s\n%s%s\n\n", fence, stats.tree, fence)
+	return err
+}
+
+// writeFile scans the file once to size the fence and to learn whether it
+// ends with a newline, then streams it.
+func (markdownFormatter) writeFile(w io.Writer, file *fileEntry) error {
+	var scanner fenceScanner
+	var size int
+	var last byte
+	err := file.scan(func(chunk []byte) {
+		scanner.write(chunk)
+		if len(chunk) > 0 {
+			size += len(chunk)
+			last = chunk[len(chunk)-1]
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", file.path, err)
+	}
+	fence := strings.Repeat("`", scanner.length())
+	language := detectLanguage(file.path, file.head)
+
+	if _, err := fmt.Fprintf(w, "## %s\n\n%s%s\n", file.relativePath, fence, language); err != nil {
+		return fmt.Errorf("error writing header for file %s: %w", file.path, err)
+	}
+
+	if _, err := io.Copy(w, file.content); err != nil {
+		return fmt.Errorf("error writing content for file %s: %w", file.path, err)
+	}
+
+	closing := fence + "\n\n"
+	if size > 0 && last != '\n' {
+		closing = "\n" + closing
+	}
+	if _, err := io.WriteString(w, closing); err != nil {
+		return fmt.Errorf("error writing footer for file %s: %w", file.path, err)
+	}
+
+	return nil
+}
+
+func (markdownFormatter) end(w io.Writer) error {
+	return nil
+}
+
+// fenceLength returns a backtick fence long enough that no run of backticks
+// inside the content can close the code block early.
+func fenceLength(content []byte) int {
+	var scanner fenceScanner
+	scanner.write(content)
+	return scanner.length()
+}
+
+// fenceScanner finds the longest run of backticks in content that arrives in
+// chunks; a run may span chunks.
+type fenceScanner struct {
+	longest, current int
+}
+
+func (s *fenceScanner) write(chunk []byte) {
+	for _, b := range chunk {
+		if b == '`' {
+			s.current++
+			s.longest = max(s.longest, s.current)
+			continue
+		}
+		s.current = 0
+	}
+}
+
+func (s *fenceScanner) length() int {
+	return max(minFenceLength, s.longest+1)
+}
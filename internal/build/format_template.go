@@ -0,0 +1,131 @@
+package build
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+)
+
+const (
+	preambleTemplateName = "preamble"
+	fileTemplateName     = "file"
+	epilogueTemplateName = "epilogue"
+)
+
+type templateStats struct {
+	FileCount int
+	TotalSize int64
+}
+
+type templateHeader struct {
+	Stats templateStats
+	Tree  string
+}
+
+type templateFile struct {
+	Index        int
+	Path         string
+	RelativePath string
+	Language     string
+	Content      string
+	Size         int
+	Stats        templateStats
+}
+
+var templateFuncs = template.FuncMap{
+	"fence": func(content string) string {
+		return strings.Repeat("`", fenceLength([]byte(content)))
+	},
+	"xml": func(s string) (string, error) {
+		var b strings.Builder
+		err := xml.EscapeText(&b, []byte(s))
+		return b.String(), err
+	},
+	"json": func(s string) (string, error) {
+		data, err := json.Marshal(s)
+		return string(data), err
+	},
+	"trimSpace": strings.TrimSpace,
+}
+
+// templateFormatter renders a user-supplied text/template. The template may
+// define "preamble", "file" and "epilogue"; when "file" is missing the body
+// of the template itself is executed for every file.
+type templateFormatter struct {
+	tmpl  *template.Template
+	stats templateStats
+}
+
+func ValidateTemplate(fsys fs.FileSystem, path string) error {
+	_, err := parseTemplate(fsys, path)
+	return err
+}
+
+func parseTemplate(fsys fs.FileSystem, path string) (*template.Template, error) {
+	source, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read template %s: %w", path, err)
+	}
+
+	tmpl, err := template.New(path).Funcs(templateFuncs).Parse(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+func newTemplateFormatter(fsys fs.FileSystem, path string) (*templateFormatter, error) {
+	tmpl, err := parseTemplate(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	return &templateFormatter{tmpl: tmpl}, nil
+}
+
+func (f *templateFormatter) begin(w io.Writer, stats *buildStats) error {
+	f.stats = templateStats{FileCount: stats.fileCount, TotalSize: stats.totalSize}
+	return f.execute(w, preambleTemplateName, templateHeader{Stats: f.stats, Tree: stats.tree})
+}
+
+// writeFile hands the content to the template as a string, so unlike the
+// other formats it reads each file into memory.
+func (f *templateFormatter) writeFile(w io.Writer, file *fileEntry) error {
+	content, err := io.ReadAll(file.content)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", file.path, err)
+	}
+	data := templateFile{
+		Index:        file.index,
+		Path:         file.path,
+		RelativePath: file.relativePath,
+		Language:     detectLanguage(file.path, content),
+		Content:      string(content),
+		Size:         len(content),
+		Stats:        f.stats,
+	}
+
+	name := fileTemplateName
+	if f.tmpl.Lookup(name) == nil {
+		name = f.tmpl.Name()
+	}
+	if err := f.execute(w, name, data); err != nil {
+		return fmt.Errorf("error rendering file %s: %w", file.path, err)
+	}
+	return nil
+}
+
+func (f *templateFormatter) end(w io.Writer) error {
+	return f.execute(w, epilogueTemplateName, templateHeader{Stats: f.stats})
+}
+
+func (f *templateFormatter) execute(w io.Writer, name string, data any) error {
+	if f.tmpl.Lookup(name) == nil {
+		return nil
+	}
+	return f.tmpl.ExecuteTemplate(w, name, data)
+}
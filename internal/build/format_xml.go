@@ -0,0 +1,128 @@
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	cdataStart = "<![CDATA["
+	cdataEnd   = "]]>"
+	// cdataSplit closes the section between "]]" and ">" and immediately
+	// reopens it, so "]]>" inside a file cannot terminate the section early.
+	cdataSplit = "]]]]><![CDATA[>"
+)
+
+type xmlFormatter struct {
+	wrap bool
+}
+
+func (f xmlFormatter) begin(w io.Writer, stats *buildStats) error {
+	if f.wrap {
+		if _, err := io.WriteString(w, "<documents>\n"); err != nil {
+			return err
+		}
+	}
+	if stats.tree == "" {
+		return nil
+	}
+	tree := append(append([]byte("<project_tree>"), cdata([]byte(stats.tree))...), "</project_tree>\n"...)
+	_, err := w.Write(tree)
+	return err
+}
+
+func (f xmlFormatter) writeFile(w io.Writer, file *fileEntry) error {
+	var source bytes.Buffer
+	if err := xml.EscapeText(&source, []byte(file.relativePath)); err != nil {
+		return fmt.Errorf("error escaping path %s: %w", file.path, err)
+	}
+
+	header := fmt.Sprintf("<document index=\"%d\">\n<source>%s</source>\n<document_content>", file.index, source.String())
+	if _, err := io.WriteString(w, header); err != nil {
+		return fmt.Errorf("error writing header for file %s: %w", file.path, err)
+	}
+
+	if err := writeCDATA(w, file.content); err != nil {
+		return fmt.Errorf("error writing content for file %s: %w", file.path, err)
+	}
+
+	if _, err := io.WriteString(w, "</document_content>\n</document>\n"); err != nil {
+		return fmt.Errorf("error writing footer for file %s: %w", file.path, err)
+	}
+
+	return nil
+}
+
+func (f xmlFormatter) end(w io.Writer) error {
+	if !f.wrap {
+		return nil
+	}
+	_, err := io.WriteString(w, "</documents>\n")
+	return err
+}
+
+// cdata wraps content in a CDATA section, see writeCDATA.
+func cdata(content []byte) []byte {
+	var out bytes.Buffer
+	writeCDATA(&out, bytes.NewReader(content))
+	return out.Bytes()
+}
+
+// writeCDATA streams r into a CDATA section. Characters that are not allowed
+// anywhere in an XML 1.0 document are replaced with U+FFFD. Empty content
+// writes nothing.
+func writeCDATA(w io.Writer, r io.Reader) error {
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+	started := false
+	// brackets counts the "]" held back because they may start "]]>".
+	brackets := 0
+	for {
+		char, size, err := in.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !started {
+			out.WriteString(cdataStart)
+			started = true
+		}
+
+		switch {
+		case char == ']':
+			brackets++
+			continue
+		case char == '>' && brackets >= 2:
+			out.WriteString(strings.Repeat("]", brackets-2) + cdataSplit)
+			brackets = 0
+			continue
+		}
+		out.WriteString(strings.Repeat("]", brackets))
+		brackets = 0
+
+		if char == utf8.RuneError && size <= 1 || !isXMLChar(char) {
+			out.WriteRune(utf8.RuneError)
+		} else {
+			out.WriteRune(char)
+		}
+	}
+	if started {
+		out.WriteString(strings.Repeat("]", brackets))
+		out.WriteString(cdataEnd)
+	}
+	return out.Flush()
+}
+
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
@@ -0,0 +1,147 @@
+package build
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+var languageByExtension = map[string]string{
+	".go":      "go",
+	".mod":     "go-mod",
+	".py":      "python",
+	".pyi":     "python",
+	".rb":      "ruby",
+	".rs":      "rust",
+	".js":      "javascript",
+	".mjs":     "javascript",
+	".cjs":     "javascript",
+	".jsx":     "jsx",
+	".ts":      "typescript",
+	".tsx":     "tsx",
+	".java":    "java",
+	".kt":      "kotlin",
+	".kts":     "kotlin",
+	".scala":   "scala",
+	".swift":   "swift",
+	".c":       "c",
+	".h":       "c",
+	".cc":      "cpp",
+	".cpp":     "cpp",
+	".cxx":     "cpp",
+	".hpp":     "cpp",
+	".cs":      "csharp",
+	".php":     "php",
+	".pl":      "perl",
+	".lua":     "lua",
+	".r":       "r",
+	".dart":    "dart",
+	".ex":      "elixir",
+	".exs":     "elixir",
+	".erl":     "erlang",
+	".hs":      "haskell",
+	".clj":     "clojure",
+	".sh":      "bash",
+	".bash":    "bash",
+	".zsh":     "zsh",
+	".fish":    "fish",
+	".ps1":     "powershell",
+	".bat":     "batch",
+	".sql":     "sql",
+	".html":    "html",
+	".htm":     "html",
+	".css":     "css",
+	".scss":    "scss",
+	".less":    "less",
+	".vue":     "vue",
+	".svelte":  "svelte",
+	".json":    "json",
+	".yaml":    "yaml",
+	".yml":     "yaml",
+	".toml":    "toml",
+	".ini":     "ini",
+	".xml":     "xml",
+	".md":      "markdown",
+	".proto":   "protobuf",
+	".tf":      "hcl",
+	".hcl":     "hcl",
+	".graphql": "graphql",
+	".diff":    "diff",
+	".patch":   "diff",
+	".tex":     "latex",
+}
+
+var languageByFilename = map[string]string{
+	"Makefile":       "makefile",
+	"GNUmakefile":    "makefile",
+	"Dockerfile":     "dockerfile",
+	"Containerfile":  "dockerfile",
+	"CMakeLists.txt": "cmake",
+	"Gemfile":        "ruby",
+	"Rakefile":       "ruby",
+	"go.sum":         "text",
+}
+
+var languageByInterpreter = map[string]string{
+	"sh":      "sh",
+	"bash":    "bash",
+	"zsh":     "zsh",
+	"fish":    "fish",
+	"python":  "python",
+	"python3": "python",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"pwsh":    "powershell",
+}
+
+// detectLanguage picks a code fence language tag by file name, extension
+// and, for extension-less scripts, the shebang line.
+func detectLanguage(path string, content []byte) string {
+	name := filepath.Base(path)
+	if language, ok := languageByFilename[name]; ok {
+		return language
+	}
+
+	if language, ok := languageByExtension[strings.ToLower(filepath.Ext(name))]; ok {
+		return language
+	}
+
+	return languageFromShebang(content)
+}
+
+func languageFromShebang(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+
+	line := content[2:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = field
+				break
+			}
+		}
+	}
+
+	if language, ok := languageByInterpreter[interpreter]; ok {
+		return language
+	}
+	// Handle versioned interpreters such as python3.12 or ruby3.2.
+	trimmed := strings.TrimRight(interpreter, "0123456789.")
+	return languageByInterpreter[trimmed]
+}
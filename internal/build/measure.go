@@ -0,0 +1,59 @@
+package build
+
+import (
+	"context"
+
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+)
+
+// Measurement is what a build of a selection would include, before any
+// token budget is applied.
+type Measurement struct {
+	Files  int
+	Bytes  int64
+	Tokens int
+}
+
+// Measure runs the discovery, size and content type checks of Build on paths
+// and counts the tokens of the files that pass, without writing anything.
+// It gives up with ctx's error as soon as ctx is done.
+func (cb *ContextBuilder) Measure(ctx context.Context, paths []string) (Measurement, error) {
+	var m Measurement
+
+	pathFilter := filter.New(cb.fsys, cb.config)
+	files, _, err := fs.DiscoverFilesParallel(ctx, cb.fsys, paths, pathFilter, cb.jobs())
+	if err != nil {
+		return m, err
+	}
+
+	sizes := make([]int64, len(files))
+	counts := make([]int, len(files))
+	included := make([]bool, len(files))
+	forEach(len(files), cb.jobs(), func(i int) {
+		if ctx.Err() != nil || cb.checkFile(files[i], true) != nil {
+			return
+		}
+		info, err := cb.fsys.Stat(files[i])
+		if err != nil {
+			return
+		}
+		count, err := cb.countFileTokens(files[i])
+		if err != nil {
+			return
+		}
+		sizes[i], counts[i], included[i] = info.Size(), count, true
+	})
+	if err := ctx.Err(); err != nil {
+		return m, err
+	}
+
+	for i := range files {
+		if included[i] {
+			m.Files++
+			m.Bytes += sizes[i]
+			m.Tokens += counts[i]
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,150 @@
+package build
+
+import (
+	"bytes"
+	"runtime"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/tokens"
+)
+
+// prefetchLimit is the largest file a worker reads ahead into memory. Larger
+// files are only opened and sniffed ahead and streamed when their turn comes,
+// which keeps the memory of a build bounded however large the files are.
+const prefetchLimit = 256 * 1024
+
+// jobs is how many files are worked on at once.
+func (cb *ContextBuilder) jobs() int {
+	if cb.config.Jobs > 0 {
+		return cb.config.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// forEach calls fn for every index below n, on up to jobs goroutines at once.
+// fn writes its result to its own index, so the results keep their order.
+func forEach(n, jobs int, fn func(i int)) {
+	var group errgroup.Group
+	group.SetLimit(jobs)
+	for i := range n {
+		group.Go(func() error {
+			fn(i)
+			return nil
+		})
+	}
+	group.Wait()
+}
+
+// preparedFile is a file opened, sniffed and, if small enough, read by a
+// worker ahead of the writer.
+type preparedFile struct {
+	source      *contentSource
+	contentType string
+	err         error
+	// tokens is the token count of a file read ahead, if counted is set.
+	tokens  int
+	counted bool
+	ready   chan struct{}
+}
+
+// prefetcher prepares the files to write on a pool of workers, never more
+// than the size of window ahead of the writer, which takes them in order.
+type prefetcher struct {
+	files  []*preparedFile
+	taken  int
+	window chan struct{}
+	stop   chan struct{}
+	group  errgroup.Group
+}
+
+// prefetch starts preparing paths in order. Files with a count in
+// tokenCounts are not counted again.
+func (cb *ContextBuilder) prefetch(paths []string, tokenCounts map[string]int) *prefetcher {
+	jobs := cb.jobs()
+	p := &prefetcher{
+		files:  make([]*preparedFile, len(paths)),
+		window: make(chan struct{}, 2*jobs),
+		stop:   make(chan struct{}),
+	}
+	for i := range p.files {
+		p.files[i] = &preparedFile{ready: make(chan struct{})}
+	}
+
+	p.group.Go(func() error {
+		var workers errgroup.Group
+		workers.SetLimit(jobs)
+		defer workers.Wait()
+		for i, path := range paths {
+			select {
+			case p.window <- struct{}{}:
+			case <-p.stop:
+				return nil
+			}
+			file := p.files[i]
+			_, counted := tokenCounts[path]
+			workers.Go(func() error {
+				defer close(file.ready)
+				cb.timings.track(path, func() {
+					cb.prepareFile(path, file, !counted)
+				})
+				return nil
+			})
+		}
+		return nil
+	})
+	return p
+}
+
+func (cb *ContextBuilder) prepareFile(path string, file *preparedFile, count bool) {
+	source, contentType, err := openContent(cb.fsys, path)
+	if err != nil {
+		file.err = err
+		return
+	}
+	file.source, file.contentType = source, contentType
+	if !fs.IsText(contentType) {
+		return
+	}
+
+	data, err := source.readAhead(prefetchLimit)
+	if err != nil {
+		source.Close()
+		file.source, file.err = nil, err
+		return
+	}
+	if data != nil && count {
+		file.tokens, _ = tokens.CountReader(cb.counter, bytes.NewReader(data))
+		file.counted = true
+	}
+}
+
+// next waits until the i-th file is prepared. Files must be taken in order.
+func (p *prefetcher) next(i int) *preparedFile {
+	p.taken = i + 1
+	file := p.files[i]
+	<-file.ready
+	return file
+}
+
+// done makes room for one more file once the writer is finished with one.
+func (p *prefetcher) done() {
+	<-p.window
+}
+
+// close stops the workers and closes the files they prepared that were never
+// taken.
+func (p *prefetcher) close() {
+	close(p.stop)
+	p.group.Wait()
+	for _, file := range p.files[p.taken:] {
+		select {
+		case <-file.ready:
+			if file.source != nil {
+				file.source.Close()
+			}
+		default:
+		}
+	}
+}
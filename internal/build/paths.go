@@ -0,0 +1,216 @@
+package build
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+)
+
+// rootMarkers identify a project root when no --root is given.
+var rootMarkers = []string{".git", "go.mod"}
+
+// pathResolver turns file paths into the forward-slash paths written to the
+// output, relative to the project root each file belongs to. When the files
+// come from several roots, every path is prefixed with the shortest trailing
+// part of its root that tells the roots apart.
+type pathResolver struct {
+	cb      *ContextBuilder
+	roots   map[string]string
+	labels  map[string]string
+	dirRoot map[string]string
+}
+
+func (cb *ContextBuilder) newPathResolver(files []string) *pathResolver {
+	r := &pathResolver{
+		cb:      cb,
+		roots:   make(map[string]string, len(files)),
+		labels:  make(map[string]string),
+		dirRoot: make(map[string]string),
+	}
+
+	explicitRoot := ""
+	if cb.config.Root != "" {
+		if absRoot, err := cb.fsys.Abs(cb.config.Root); err == nil {
+			explicitRoot = absRoot
+		}
+	}
+
+	distinct := make(map[string]struct{})
+	for _, path := range files {
+		absPath, err := cb.fsys.Abs(path)
+		if err != nil {
+			continue
+		}
+
+		root := ""
+		if explicitRoot != "" && isWithin(explicitRoot, absPath) {
+			root = explicitRoot
+		} else {
+			root = r.detectRoot(filepath.Dir(absPath))
+		}
+		r.roots[path] = root
+		distinct[root] = struct{}{}
+	}
+
+	// A root nested inside another one (a go.mod in a sub-directory of a git
+	// repository) is folded into the outer root.
+	for path, root := range r.roots {
+		for other := range distinct {
+			if other != root && isWithin(other, root) {
+				root = other
+			}
+		}
+		r.roots[path] = root
+	}
+
+	outer := make([]string, 0, len(distinct))
+	for _, root := range r.roots {
+		if _, ok := r.labels[root]; !ok {
+			r.labels[root] = ""
+			outer = append(outer, root)
+		}
+	}
+	if len(outer) > 1 {
+		sort.Strings(outer)
+		for root, label := range uniqueSuffixes(outer) {
+			r.labels[root] = label
+		}
+	}
+
+	return r
+}
+
+// display returns the path written into file headers.
+func (r *pathResolver) display(path string) string {
+	root, ok := r.roots[path]
+	if !ok {
+		return filepath.ToSlash(path)
+	}
+
+	absPath, err := r.cb.fsys.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	relPath, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return filepath.ToSlash(absPath)
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	if label := r.labels[root]; label != "" {
+		return label + "/" + relPath
+	}
+	return relPath
+}
+
+// detectRoot walks up from dir to the nearest directory containing one of
+// the root markers. Without a marker, the directory itself is the root.
+func (r *pathResolver) detectRoot(dir string) string {
+	var visited []string
+	root := dir
+	for current := dir; ; current = filepath.Dir(current) {
+		if cached, ok := r.dirRoot[current]; ok {
+			root = cached
+			break
+		}
+		visited = append(visited, current)
+
+		if r.hasRootMarker(current) {
+			root = current
+			break
+		}
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+
+	for _, visitedDir := range visited {
+		r.dirRoot[visitedDir] = root
+	}
+	return root
+}
+
+func (r *pathResolver) hasRootMarker(dir string) bool {
+	return hasRootMarker(r.cb.fsys, dir)
+}
+
+// ProjectRoot returns the nearest directory at or above dir that contains
+// one of the root markers, or dir itself when there is none.
+func ProjectRoot(fsys fs.FileSystem, dir string) (string, error) {
+	absDir, err := fsys.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for current := absDir; ; current = filepath.Dir(current) {
+		if hasRootMarker(fsys, current) {
+			return current, nil
+		}
+		if filepath.Dir(current) == current {
+			return absDir, nil
+		}
+	}
+}
+
+func hasRootMarker(fsys fs.FileSystem, dir string) bool {
+	for _, marker := range rootMarkers {
+		if _, err := fsys.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueSuffixes labels every root with as few trailing path components as
+// needed to make all labels distinct, e.g. "api" and "web", or "a/app" and
+// "b/app".
+func uniqueSuffixes(roots []string) map[string]string {
+	parts := make(map[string][]string, len(roots))
+	for _, root := range roots {
+		parts[root] = splitPath(root)
+	}
+
+	labels := make(map[string]string, len(roots))
+	for _, root := range roots {
+		components := parts[root]
+		for n := 1; n <= len(components); n++ {
+			label := suffix(components, n)
+			unique := true
+			for _, other := range roots {
+				if other != root && suffix(parts[other], n) == label {
+					unique = false
+					break
+				}
+			}
+			if unique || n == len(components) {
+				labels[root] = label
+				break
+			}
+		}
+	}
+	return labels
+}
+
+func suffix(components []string, n int) string {
+	if n > len(components) {
+		n = len(components)
+	}
+	tail := components[len(components)-n:]
+	trimmed := make([]string, 0, len(tail))
+	for _, component := range tail {
+		component = strings.Trim(filepath.ToSlash(component), "/")
+		if component != "" {
+			trimmed = append(trimmed, component)
+		}
+	}
+	return strings.Join(trimmed, "/")
+}
+
+func isWithin(dir, path string) bool {
+	relPath, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return relPath == "." || relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,182 @@
+package build
+
+import (
+	"encoding/json"
+	"io"
+	iofs "io/fs"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+)
+
+// Decisions of a report entry.
+const (
+	DecisionIncluded   = "included"
+	DecisionDropped    = "dropped"
+	DecisionBinary     = "binary"
+	DecisionTooLarge   = "too_large"
+	DecisionExcluded   = "excluded"
+	DecisionUnreadable = "unreadable"
+	DecisionWriteError = "write_error"
+)
+
+// ReportEntry is what happened to one discovered path.
+type ReportEntry struct {
+	Path     string        `json:"path"`
+	Dir      bool          `json:"dir,omitempty"`
+	Decision string        `json:"decision"`
+	Detail   string        `json:"detail,omitempty"`
+	Size     int64         `json:"size"`
+	Written  int64         `json:"written,omitempty"`
+	Tokens   int           `json:"tokens,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// ReportTotals sums up a report.
+type ReportTotals struct {
+	Files    int   `json:"files"`
+	Included int   `json:"included"`
+	Skipped  int   `json:"skipped"`
+	Failed   int   `json:"failed"`
+	Bytes    int64 `json:"bytes"`
+	Tokens   int   `json:"tokens"`
+}
+
+// Report lists every path a build looked at with its decision, sorted by
+// path. Excluded directories are listed once, not with everything below them.
+type Report struct {
+	Output    string        `json:"output"`
+	Format    string        `json:"format"`
+	Tokenizer string        `json:"tokenizer"`
+	Duration  time.Duration `json:"duration_ns"`
+	Totals    ReportTotals  `json:"totals"`
+	Files     []ReportEntry `json:"files"`
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+var failureDecisions = map[FailureReason]string{
+	ReadFailed:      DecisionUnreadable,
+	WriteFailed:     DecisionWriteError,
+	SkippedBinary:   DecisionBinary,
+	SkippedTooLarge: DecisionTooLarge,
+	SkippedExcluded: DecisionExcluded,
+}
+
+// newReport assembles the report of a finished build from its result, the
+// paths discovery excluded and the time spent per file.
+func (cb *ContextBuilder) newReport(result *BuildResult, excluded []ReportEntry, outputFilename string, started time.Time) *Report {
+	report := &Report{
+		Output:    outputFilename,
+		Format:    cb.config.Format,
+		Tokenizer: result.Tokenizer,
+		Duration:  time.Since(started),
+	}
+	seen := make(map[string]bool)
+	add := func(entry ReportEntry) {
+		if seen[entry.Path] {
+			return
+		}
+		seen[entry.Path] = true
+		entry.Duration = cb.timings.spent(entry.Path)
+		report.Files = append(report.Files, entry)
+	}
+
+	for _, file := range result.Files {
+		add(ReportEntry{Path: file.Path, Decision: DecisionIncluded, Size: file.Size, Written: file.Written, Tokens: file.Tokens})
+	}
+	for _, file := range result.DroppedFiles {
+		add(ReportEntry{Path: file.Path, Decision: DecisionDropped, Size: cb.size(file.Path), Tokens: file.Tokens, Detail: "over the token budget"})
+	}
+	for _, failure := range result.Failures {
+		add(ReportEntry{Path: failure.Path, Decision: failureDecisions[failure.Reason], Detail: failure.Detail, Size: cb.size(failure.Path)})
+	}
+	for _, entry := range excluded {
+		if !entry.Dir {
+			entry.Size = cb.size(entry.Path)
+		}
+		add(entry)
+	}
+
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+	for _, entry := range report.Files {
+		report.Totals.Files++
+		switch entry.Decision {
+		case DecisionIncluded:
+			report.Totals.Included++
+			report.Totals.Bytes += entry.Size
+			report.Totals.Tokens += entry.Tokens
+		case DecisionUnreadable, DecisionWriteError:
+			report.Totals.Failed++
+		default:
+			report.Totals.Skipped++
+		}
+	}
+	return report
+}
+
+func (cb *ContextBuilder) size(path string) int64 {
+	info, err := cb.fsys.Stat(path)
+	if err != nil || info.IsDir() {
+		return 0
+	}
+	return info.Size()
+}
+
+// recordingFilter remembers every path the filter excludes during discovery,
+// which would otherwise vanish without a trace.
+// Discovery runs concurrently, hence the mutex.
+type recordingFilter struct {
+	*filter.Filter
+	mu       sync.Mutex
+	excluded []ReportEntry
+}
+
+func (f *recordingFilter) Excludes(path string, entry iofs.DirEntry) bool {
+	decision := f.Decide(path, entry)
+	if decision.Excluded() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.excluded = append(f.excluded, ReportEntry{
+			Path:     path,
+			Dir:      entry.IsDir(),
+			Decision: DecisionExcluded,
+			Detail:   describeDecision(decision),
+		})
+	}
+	return decision.Excluded()
+}
+
+// fileTimings adds up the time spent on each file across the build stages.
+type fileTimings struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+}
+
+func newFileTimings() *fileTimings {
+	return &fileTimings{durations: make(map[string]time.Duration)}
+}
+
+// track runs fn and charges the time it takes to path.
+func (t *fileTimings) track(path string, fn func()) {
+	start := time.Now()
+	fn()
+	t.mu.Lock()
+	t.durations[path] += time.Since(start)
+	t.mu.Unlock()
+}
+
+func (t *fileTimings) spent(path string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.durations[path]
+}
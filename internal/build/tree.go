@@ -0,0 +1,245 @@
+package build
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+)
+
+const (
+	treeBranch     = "├── "
+	treeLastBranch = "└── "
+	treeIndent     = "│   "
+	treeLastIndent = "    "
+)
+
+const (
+	treeMarkIncluded = "[included]"
+	treeMarkExcluded = "[excluded]"
+	treeMarkBinary   = "[binary]"
+	treeMarkDropped  = "[dropped]"
+	treeMarkTooLarge = "[too large]"
+	treeMarkError    = "[error]"
+)
+
+// treeMarks records, by absolute path, what happened to every file the
+// build looked at, so the tree can annotate it.
+type treeMarks map[string]string
+
+type treeWriter struct {
+	cb       *ContextBuilder
+	filter   *filter.Filter
+	marks    treeMarks
+	relevant map[string]struct{}
+	maxDepth int
+	maxItems int
+	out      strings.Builder
+}
+
+// skippedTreeMarks are the marks of the files left out before writing.
+var skippedTreeMarks = map[FailureReason]string{
+	SkippedBinary:   treeMarkBinary,
+	SkippedTooLarge: treeMarkTooLarge,
+	ReadFailed:      treeMarkError,
+}
+
+func (cb *ContextBuilder) treeMarks(textFiles []string, skipped []FileFailure, dropped []FileResult) treeMarks {
+	marks := make(treeMarks, len(textFiles)+len(skipped))
+	mark := func(path, value string) {
+		if absPath, err := cb.fsys.Abs(path); err == nil {
+			marks[absPath] = value
+		}
+	}
+
+	for _, failure := range skipped {
+		if value, ok := skippedTreeMarks[failure.Reason]; ok {
+			mark(failure.Path, value)
+		}
+	}
+	for _, file := range dropped {
+		mark(file.Path, treeMarkDropped)
+	}
+	for _, path := range textFiles {
+		mark(path, treeMarkIncluded)
+	}
+	return marks
+}
+
+// buildTree renders a `tree`-style map of the directory that is the common
+// ancestor of all selected paths.
+func (cb *ContextBuilder) buildTree(selectedPaths []string, marks treeMarks, pathFilter *filter.Filter) (string, error) {
+	root, err := cb.commonAncestor(selectedPaths)
+	if err != nil {
+		return "", err
+	}
+
+	tw := &treeWriter{
+		cb:       cb,
+		filter:   pathFilter,
+		marks:    marks,
+		relevant: marks.ancestors(),
+		maxDepth: cb.config.TreeDepth,
+		maxItems: cb.config.TreeMaxEntries,
+	}
+	label := filepath.Base(root) + "/"
+	if filepath.Dir(root) == root {
+		label = root
+	}
+	tw.out.WriteString(label + "\n")
+	tw.writeDir(root, "", 1)
+
+	return tw.out.String(), nil
+}
+
+func (tw *treeWriter) writeDir(dir, prefix string, depth int) {
+	entries, err := tw.cb.fsys.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(&tw.out, "%s%s(unreadable: %v)\n", prefix, treeLastBranch, err)
+		return
+	}
+
+	shown := tw.limitEntries(dir, entries)
+
+	for i, entry := range shown {
+		isLast := i == len(shown)-1 && len(shown) == len(entries)
+		branch, childPrefix := treeBranch, prefix+treeIndent
+		if isLast {
+			branch, childPrefix = treeLastBranch, prefix+treeLastIndent
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+
+		mark := tw.marks[path]
+		if mark == "" && tw.filter.Excludes(path, entry) {
+			mark = treeMarkExcluded
+		}
+
+		line := prefix + branch + name
+		if mark != "" {
+			line += "  " + mark
+		}
+		tw.out.WriteString(line + "\n")
+
+		// An excluded directory is only expanded when something inside it
+		// was selected explicitly.
+		_, relevant := tw.relevant[path]
+		if !entry.IsDir() || mark == treeMarkExcluded && !relevant {
+			continue
+		}
+		if tw.maxDepth > 0 && depth >= tw.maxDepth {
+			continue
+		}
+		tw.writeDir(path, childPrefix, depth+1)
+	}
+
+	if hidden := len(entries) - len(shown); hidden > 0 {
+		fmt.Fprintf(&tw.out, "%s%s… %d more\n", prefix, treeLastBranch, hidden)
+	}
+}
+
+// limitEntries applies the per-directory cap. Entries that lead to files the
+// build looked at are always kept, the remaining slots go to the others in
+// their original order.
+func (tw *treeWriter) limitEntries(dir string, entries []iofs.DirEntry) []iofs.DirEntry {
+	if tw.maxItems == 0 || len(entries) <= tw.maxItems {
+		return entries
+	}
+
+	keep := make([]bool, len(entries))
+	kept := 0
+	for i, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		_, marked := tw.marks[path]
+		_, relevant := tw.relevant[path]
+		if marked || relevant {
+			keep[i] = true
+			kept++
+		}
+	}
+	for i := range entries {
+		if kept >= tw.maxItems {
+			break
+		}
+		if !keep[i] {
+			keep[i] = true
+			kept++
+		}
+	}
+
+	shown := make([]iofs.DirEntry, 0, kept)
+	for i, entry := range entries {
+		if keep[i] {
+			shown = append(shown, entry)
+		}
+	}
+	return shown
+}
+
+// ancestors returns every directory that contains a marked file.
+func (marks treeMarks) ancestors() map[string]struct{} {
+	dirs := make(map[string]struct{})
+	for path := range marks {
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			if _, seen := dirs[dir]; seen {
+				break
+			}
+			dirs[dir] = struct{}{}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return dirs
+}
+
+func (cb *ContextBuilder) commonAncestor(paths []string) (string, error) {
+	var common []string
+	for i, path := range paths {
+		absPath, err := cb.fsys.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve path %s: %w", path, err)
+		}
+
+		dir := absPath
+		if info, err := cb.fsys.Stat(absPath); err != nil || !info.IsDir() {
+			dir = filepath.Dir(absPath)
+		}
+
+		parts := splitPath(dir)
+		if i == 0 {
+			common = parts
+			continue
+		}
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+
+	if len(common) == 0 {
+		return string(filepath.Separator), nil
+	}
+	return filepath.Join(common...), nil
+}
+
+// splitPath breaks a clean absolute path into its volume-rooted components,
+// e.g. "/a/b" -> ["/", "a", "b"] and `C:\a` -> [`C:\`, "a"].
+func splitPath(path string) []string {
+	path = filepath.Clean(path)
+	volume := filepath.VolumeName(path)
+	rest := strings.TrimPrefix(path[len(volume):], string(filepath.Separator))
+
+	parts := []string{volume + string(filepath.Separator)}
+	if rest != "" {
+		parts = append(parts, strings.Split(rest, string(filepath.Separator))...)
+	}
+	return parts
+}
@@ -3,12 +3,18 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/kacperzielinskidev/getctx/internal/build"
 	"github.com/kacperzielinskidev/getctx/internal/config"
 	"github.com/kacperzielinskidev/getctx/internal/core"
 	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
 	"github.com/kacperzielinskidev/getctx/internal/logger"
+	"github.com/kacperzielinskidev/getctx/internal/selection"
+	"github.com/kacperzielinskidev/getctx/internal/tokens"
 )
 
 func Run() error {
@@ -20,10 +26,65 @@ func Run() error {
 
 	log := logger.New(cfg.logOutput, cfg.logLevel)
 	fsys := fs.NewOSFileSystem()
-	appConfig := config.NewConfig()
 
-	contextBuilder := build.NewContextBuilder(log, fsys, appConfig)
-	app := core.NewApp(log, contextBuilder, appConfig, fsys, cfg.startPath, cfg.outputFilename)
+	if cfg.command == commandConfig {
+		return runConfigCommand(fsys, cfg)
+	}
+	appConfig, err := loadConfig(fsys, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := setupExclusions(fsys, appConfig, cfg.startPath); err != nil {
+		return err
+	}
+	if err := loadProjectRules(fsys, appConfig, cfg.startPath); err != nil {
+		return err
+	}
+
+	if appConfig.Template != "" {
+		if err := build.ValidateTemplate(fsys, appConfig.Template); err != nil {
+			return fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+	}
+
+	counter, err := newTokenCounter(fsys, appConfig.TokenizerVocab)
+	if err != nil {
+		return err
+	}
+
+	contextBuilder := build.NewContextBuilder(log, fsys, appConfig, counter)
+	if cfg.command == commandWhy {
+		return explainPath(contextBuilder, cfg.paths[0])
+	}
+
+	if cfg.command == commandBuild {
+		paths, err := collectBuildPaths(fsys, cfg, appConfig)
+		if err != nil {
+			return err
+		}
+		app := core.NewApp(log, contextBuilder, appConfig, fsys, cfg.startPath, appConfig.OutputFilename, nil)
+		result, err := app.RunHeadless(paths)
+		if err != nil {
+			return reportFailedBuild(fsys, cfg, result, err)
+		}
+		if err := exportSelection(fsys, cfg, appConfig, app.Selection()); err != nil {
+			return err
+		}
+		if err := reportBuild(fsys, cfg, result); err != nil {
+			return err
+		}
+		if err := presentResults(result, appConfig.OutputFilename); err != nil {
+			return err
+		}
+		return buildStatus(result)
+	}
+
+	initialSelection, err := importSelection(fsys, cfg, appConfig)
+	if err != nil {
+		return err
+	}
+	app := core.NewApp(log, contextBuilder, appConfig, fsys, cfg.startPath, appConfig.OutputFilename, initialSelection)
 
 	result, err := app.Run()
 	if err != nil {
@@ -31,40 +92,273 @@ func Run() error {
 			fmt.Println("Operation cancelled.")
 			return nil
 		}
+		return reportFailedBuild(fsys, cfg, result, err)
+	}
+
+	if err := exportSelection(fsys, cfg, appConfig, app.Selection()); err != nil {
 		return err
 	}
+	if err := reportBuild(fsys, cfg, result); err != nil {
+		return err
+	}
+	if err := presentResults(result, appConfig.OutputFilename); err != nil {
+		return err
+	}
+	return buildStatus(result)
+}
+
+// setupExclusions anchors the exclusion patterns at the project root.
+func setupExclusions(fsys fs.FileSystem, appConfig *config.Config, startPath string) error {
+	var base string
+	var err error
+	if appConfig.Root != "" {
+		base, err = fsys.Abs(appConfig.Root)
+	} else {
+		base, err = build.ProjectRoot(fsys, startPath)
+	}
+	if err != nil {
+		return fmt.Errorf("could not resolve project root: %w", err)
+	}
+	appConfig.Exclude.Base = base
+	if appConfig.ProfileInclude != nil {
+		appConfig.ProfileInclude.Base = base
+	}
+	return nil
+}
+
+// loadProjectRules picks up the .getctxignore and .getctxinclude files that
+// apply to the start path.
+func loadProjectRules(fsys fs.FileSystem, appConfig *config.Config, startPath string) error {
+	ignoreRules, err := ignore.LoadProjectRules(fsys, startPath, ignore.ProjectIgnoreFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	includeRules, err := ignore.LoadProjectRules(fsys, startPath, ignore.ProjectIncludeFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+
+	appConfig.IgnoreRules = ignoreRules
+	appConfig.IncludeRules = includeRules
+	return nil
+}
 
-	return presentResults(result, cfg.outputFilename)
+// newTokenCounter loads a BPE vocabulary when one is configured and falls
+// back to the heuristic counter otherwise.
+func newTokenCounter(fsys fs.FileSystem, vocabPath string) (tokens.Counter, error) {
+	if vocabPath == "" {
+		return tokens.NewHeuristicCounter(), nil
+	}
 
+	file, err := fsys.Open(vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open tokenizer vocabulary %s: %w", vocabPath, err)
+	}
+	defer file.Close()
+
+	name := strings.TrimSuffix(filepath.Base(vocabPath), filepath.Ext(vocabPath))
+	counter, err := tokens.LoadBPE(name, file)
+	if err != nil {
+		return nil, fmt.Errorf("could not load tokenizer vocabulary %s: %w", vocabPath, err)
+	}
+	return counter, nil
 }
 
+// collectBuildPaths merges the positional paths with the entries of every path
+// list given via '-' or --from-file and of the imported selection list. Without
+// any of them, a profile builds its own paths, or the whole project if it
+// lists none.
+func collectBuildPaths(fsys fs.FileSystem, cfg *flagConfig, appConfig *config.Config) ([]string, error) {
+	paths := append([]string(nil), cfg.paths...)
+	if cfg.importList != "" {
+		list, err := selection.Load(fsys, cfg.importList)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+		listed, err := listPaths(fsys, list, appConfig.Exclude.Base)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrUsage, cfg.importList, err)
+		}
+		paths = append(paths, listed...)
+	}
+	if len(paths) == 0 && len(cfg.pathLists) == 0 {
+		if profilePaths := appConfig.ProfilePaths(); len(profilePaths) > 0 {
+			return profilePaths, nil
+		}
+		return []string{appConfig.Exclude.Base}, nil
+	}
+
+	for _, source := range cfg.pathLists {
+		listed, err := readPathList(fsys, source, appConfig.Exclude.Base)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, listed...)
+	}
+
+	return paths, nil
+}
+
+// readPathList reads a plain or JSON path list. Like an imported list, its
+// relative paths are taken relative to the project root, so an exported list
+// reads back the same from any directory.
+func readPathList(fsys fs.FileSystem, source, root string) ([]string, error) {
+	if source == stdinPath {
+		list, err := selection.Read(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return listPaths(fsys, list, root)
+	}
+
+	file, err := fsys.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("could not open path list %s: %w", source, err)
+	}
+	defer file.Close()
+
+	list, err := selection.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	paths, err := listPaths(fsys, list, root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return paths, nil
+}
+
+// listPaths returns the paths of a list to build. Missing entries are kept,
+// so the build reports them as failed paths, but an entry that no longer has
+// its listed mode is an error.
+func listPaths(fsys fs.FileSystem, list *selection.List, root string) ([]string, error) {
+	if err := list.CheckModes(fsys, root); err != nil {
+		return nil, err
+	}
+	return list.Paths(root), nil
+}
+
+// importSelection loads the list to preselect in the file browser. Entries
+// that no longer exist are reported and left out.
+func importSelection(fsys fs.FileSystem, cfg *flagConfig, appConfig *config.Config) ([]string, error) {
+	if cfg.importList == "" {
+		return nil, nil
+	}
+
+	list, err := selection.Load(fsys, cfg.importList)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	paths, missing := list.Resolve(fsys, appConfig.Exclude.Base)
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️ %d path(s) of %s no longer exist:\n", len(missing), cfg.importList)
+		for _, path := range missing {
+			fmt.Fprintf(os.Stderr, "   - %s\n", path)
+		}
+	}
+	return paths, nil
+}
+
+// exportSelection writes the final selection to the list given by -export.
+func exportSelection(fsys fs.FileSystem, cfg *flagConfig, appConfig *config.Config, paths []string) error {
+	if cfg.exportList == "" {
+		return nil
+	}
+
+	list := selection.New(fsys, appConfig.Exclude.Base, paths)
+	if err := list.Save(fsys, cfg.exportList); err != nil {
+		return err
+	}
+	fmt.Printf("📋 Exported %d path(s) to %s\n", len(list.Entries), cfg.exportList)
+	return nil
+}
+
+// buildStatus turns the outcome of a headless build into an error that
+// carries the matching exit code, so scripts can tell the cases apart.
+func buildStatus(result *build.BuildResult) error {
+	if result.FilesProcessed == 0 {
+		return ErrNothingIncluded
+	}
+	if len(result.Errors()) > 0 || len(result.Warnings) > 0 {
+		return ErrPartialBuild
+	}
+	return nil
+}
+
+// maxSkippedListed caps how many skipped files are named one by one; binary
+// files in a large directory would drown everything else.
+const maxSkippedListed = 10
+
 func presentResults(result *build.BuildResult, outputFilename string) error {
 	// This case will occur if the user has not selected any files.
-	if result.FilesProcessed == 0 && len(result.PathsWithErr) == 0 {
+	if result.FilesProcessed == 0 && len(result.Failures) == 0 && len(result.Warnings) == 0 {
 		fmt.Println("ℹ️ No files selected. The output file was not created.")
 		return nil
 	}
 
-	if len(result.PathsWithErr) > 0 {
-		fmt.Println("⚠️ Some paths were skipped due to errors:")
-		for _, warn := range result.PathsWithErr {
+	errs := result.Errors()
+	if len(errs) > 0 || len(result.Warnings) > 0 {
+		fmt.Println("⚠️ Some paths could not be processed:")
+		for _, failure := range errs {
+			fmt.Printf("   - %s\n", failure)
+		}
+		for _, warn := range result.Warnings {
 			fmt.Printf("   - %s\n", warn)
 		}
 		fmt.Println() // Additional empty line for readability
 	}
 
-	if result.FilesProcessed == 0 {
-		message := "ℹ️ No text files found to include."
-		if result.FilesSkipped > 0 {
-			message += fmt.Sprintf(" %d file(s) were skipped (non-text or unreadable).", result.FilesSkipped)
+	if skipped := result.Skipped(); len(skipped) > 0 {
+		fmt.Printf("ℹ️ Skipped %d path(s): %s\n", len(skipped), countReasons(skipped))
+		for i, failure := range skipped {
+			if i == maxSkippedListed {
+				fmt.Printf("   ... and %d more\n", len(skipped)-maxSkippedListed)
+				break
+			}
+			fmt.Printf("   - %s\n", failure)
 		}
-		message += " The output file was not created."
-		fmt.Println(message)
+		fmt.Println()
+	}
+
+	if result.FilesProcessed == 0 {
+		fmt.Println("ℹ️ No text files found to include. The output file was not created.")
 		return nil
 	}
 
+	if len(result.DroppedFiles) > 0 {
+		fmt.Printf("✂️ Dropped %d file(s) to stay within the token budget:\n", len(result.DroppedFiles))
+		for _, file := range result.DroppedFiles {
+			fmt.Printf("   - %s (%d tokens)\n", file.Path, file.Tokens)
+		}
+		fmt.Println()
+	}
+
 	fmt.Printf("🚀 Processing finished. Found %d files to process.\n", result.FilesProcessed)
-	fmt.Printf("✅ Done! All content has been combined into the file %s\n", outputFilename)
+	fmt.Printf("🔢 Tokens: %d (%s)\n", result.TotalTokens, result.Tokenizer)
+	if len(errs) > 0 {
+		fmt.Printf("⚠️ Done, but %d path(s) could not be included in %s\n", len(errs), outputFilename)
+	} else {
+		fmt.Printf("✅ Done! All content has been combined into the file %s\n", outputFilename)
+	}
 
 	return nil
 }
+
+// countReasons summarizes failures as "3 binary, 1 too large", in the order
+// the reasons first appear.
+func countReasons(failures []build.FileFailure) string {
+	counts := make(map[build.FailureReason]int)
+	var order []build.FailureReason
+	for _, failure := range failures {
+		if counts[failure.Reason] == 0 {
+			order = append(order, failure.Reason)
+		}
+		counts[failure.Reason]++
+	}
+
+	parts := make([]string, len(order))
+	for i, reason := range order {
+		parts[i] = fmt.Sprintf("%d %s", counts[reason], reason)
+	}
+	return strings.Join(parts, ", ")
+}
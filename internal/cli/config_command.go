@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kacperzielinskidev/getctx/internal/build"
+	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
+)
+
+// rulesLineWidth is where long lists of patterns are wrapped in `config show`.
+const rulesLineWidth = 100
+
+func runConfigCommand(fsys fs.FileSystem, cfg *flagConfig) error {
+	switch cfg.configAction {
+	case configShow:
+		return showConfig(fsys, cfg)
+	case configInit:
+		return initConfig(fsys, cfg)
+	default:
+		return validateConfigFiles(fsys, cfg)
+	}
+}
+
+func showConfig(fsys fs.FileSystem, cfg *flagConfig) error {
+	appConfig, err := loadConfig(fsys, cfg)
+	if err != nil {
+		return fmt.Errorf("%w (run 'getctx config validate' for details)", err)
+	}
+	if err := setupExclusions(fsys, appConfig, cfg.startPath); err != nil {
+		return err
+	}
+	if err := loadProjectRules(fsys, appConfig, cfg.startPath); err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(out, "Layers (later ones win):")
+	fmt.Fprintf(out, "  built-in defaults\n")
+	files := configFiles(fsys, cfg.startPath)
+	if len(files) == 0 {
+		fmt.Fprintf(out, "  no configuration files found\n")
+	}
+	for _, path := range files {
+		fmt.Fprintf(out, "  %s\n", path)
+	}
+	fmt.Fprintf(out, "  %s (GETCTX_*)\n", sourceEnv)
+	fmt.Fprintf(out, "  %s\n", sourceFlags)
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, "Settings:")
+	for _, setting := range appConfig.Settings() {
+		fmt.Fprintf(out, "  %s\t= %s\t# %s\n", setting.Key, setting.Value, setting.Origin)
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintf(out, "Exclusion rules, relative to %s (later ones win):\n", appConfig.Exclude.Base)
+	writePatterns(out, appConfig.Exclude.Patterns)
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, "Project rule files:")
+	ruleFiles := append(append([]*ignore.Rules(nil), appConfig.IgnoreRules...), appConfig.IncludeRules...)
+	if len(ruleFiles) == 0 {
+		fmt.Fprintf(out, "  none (%s, %s)\n", ignore.ProjectIgnoreFile, ignore.ProjectIncludeFile)
+	}
+	for _, rules := range ruleFiles {
+		writePatterns(out, rules.Patterns)
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, "Profiles:")
+	if len(appConfig.Profiles) == 0 {
+		fmt.Fprintf(out, "  none\n")
+	}
+	for _, name := range appConfig.ProfileNames() {
+		profile := appConfig.Profiles[name]
+		fmt.Fprintf(out, "  %s\t%s\t# %s\n", name, profile.Description, appConfig.Origin("profiles."+name))
+	}
+	if appConfig.ProfileInclude != nil {
+		fmt.Fprintf(out, "\nInclude patterns of profile %s:\n", appConfig.Profile)
+		writePatterns(out, appConfig.ProfileInclude.Patterns)
+	}
+
+	return out.Flush()
+}
+
+// writePatterns prints patterns grouped by the file or layer they came from,
+// wrapping long groups such as the built-in defaults.
+func writePatterns(out *tabwriter.Writer, patterns []*ignore.Pattern) {
+	for start := 0; start < len(patterns); {
+		source := patterns[start].Source
+		end := start
+		var texts []string
+		for end < len(patterns) && patterns[end].Source == source {
+			texts = append(texts, patterns[end].Text)
+			end++
+		}
+
+		var line strings.Builder
+		for _, text := range texts {
+			if line.Len() > 0 && line.Len()+len(text) > rulesLineWidth {
+				fmt.Fprintf(out, "  %s\t%s\n", source, line.String())
+				line.Reset()
+			}
+			if line.Len() > 0 {
+				line.WriteByte(' ')
+			}
+			line.WriteString(text)
+		}
+		fmt.Fprintf(out, "  %s\t%s\n", source, line.String())
+		start = end
+	}
+}
+
+func initConfig(fsys fs.FileSystem, cfg *flagConfig) error {
+	path := config.ProjectConfigFile
+	if cfg.initUser {
+		dir := config.UserConfigDir(fsys)
+		if dir == "" {
+			return fmt.Errorf("could not determine the user configuration directory")
+		}
+		if err := fsys.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("could not create %s: %w", dir, err)
+		}
+		path = filepath.Join(dir, config.UserConfigFile)
+	}
+
+	if _, err := fsys.Stat(path); err == nil && !cfg.initForce {
+		return fmt.Errorf("%w: %s already exists (use -force to overwrite it)", ErrUsage, path)
+	}
+
+	file, err := fsys.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	if _, err := file.Write([]byte(config.StarterFile)); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	fmt.Printf("📝 Wrote a starter configuration to %s\n", path)
+	return nil
+}
+
+// validateConfigFiles checks every layer and rule file and reports all
+// problems at once.
+func validateConfigFiles(fsys fs.FileSystem, cfg *flagConfig) error {
+	var problems []error
+	appConfig := config.NewConfig()
+
+	files := configFiles(fsys, cfg.startPath)
+	for _, path := range files {
+		data, err := fsys.ReadFile(path)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if errs := config.CheckFile(data, path); len(errs) > 0 {
+			problems = append(problems, errs...)
+			continue
+		}
+		if file, err := config.LoadFile(fsys, path); err != nil {
+			problems = append(problems, err)
+		} else if err := appConfig.Apply(file, path); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if env, err := config.FromEnv(os.Getenv); err != nil {
+		problems = append(problems, splitErrors(err)...)
+	} else if err := appConfig.Apply(env, sourceEnv); err != nil {
+		problems = append(problems, err)
+	}
+	if err := appConfig.Apply(cfg.overrides, sourceFlags); err != nil {
+		problems = append(problems, err)
+	}
+
+	if err := validateConfig(appConfig); err != nil {
+		problems = append(problems, splitErrors(err)...)
+	}
+	if err := validateProfiles(appConfig); err != nil {
+		problems = append(problems, splitErrors(err)...)
+	}
+	if appConfig.Template != "" {
+		if err := build.ValidateTemplate(fsys, appConfig.Template); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	for _, name := range []string{ignore.ProjectIgnoreFile, ignore.ProjectIncludeFile} {
+		if _, err := ignore.LoadProjectRules(fsys, cfg.startPath, name); err != nil {
+			problems = append(problems, splitErrors(err)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Println("❌ Found problems in the configuration:")
+		for _, problem := range problems {
+			fmt.Printf("   - %v\n", problem)
+		}
+		return fmt.Errorf("%w: %d configuration problem(s)", ErrUsage, len(problems))
+	}
+
+	if len(files) == 0 {
+		fmt.Println("✅ No configuration files found; the built-in defaults are valid.")
+	} else {
+		fmt.Printf("✅ Configuration is valid (%s).\n", strings.Join(files, ", "))
+	}
+	return nil
+}
+
+// splitErrors unpacks an errors.Join result so each problem gets its own line.
+func splitErrors(err error) []error {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
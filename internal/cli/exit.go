@@ -0,0 +1,39 @@
+package cli
+
+import "errors"
+
+const (
+	ExitOK              = 0
+	ExitFatal           = 1
+	ExitUsage           = 2
+	ExitNothingIncluded = 3
+	ExitPartial         = 4
+	// ExitPathExcluded is the verdict of `why` for a path that would be left
+	// out, kept apart from a build that produced nothing.
+	ExitPathExcluded = 5
+)
+
+var (
+	ErrUsage           = errors.New("invalid usage")
+	ErrNothingIncluded = errors.New("no files were included in the context")
+	ErrPartialBuild    = errors.New("the context was written, but some paths could not be processed")
+	ErrPathExcluded    = errors.New("the path would be left out of the context")
+)
+
+// ExitCode maps an error returned by Run to the process exit status.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrUsage):
+		return ExitUsage
+	case errors.Is(err, ErrNothingIncluded):
+		return ExitNothingIncluded
+	case errors.Is(err, ErrPathExcluded):
+		return ExitPathExcluded
+	case errors.Is(err, ErrPartialBuild):
+		return ExitPartial
+	default:
+		return ExitFatal
+	}
+}
@@ -6,15 +6,54 @@ import (
 	"io"
 	"os"
 	"runtime/pprof"
+	"slices"
+	"strings"
 
+	"github.com/kacperzielinskidev/getctx/internal/build"
+	"github.com/kacperzielinskidev/getctx/internal/config"
 	"github.com/kacperzielinskidev/getctx/internal/logger"
 )
 
+const (
+	commandInteractive = ""
+	commandBuild       = "build"
+	commandConfig      = "config"
+	commandWhy         = "why"
+)
+
+const (
+	configShow     = "show"
+	configInit     = "init"
+	configValidate = "validate"
+)
+
+// stdinPath is the positional argument (and --from-file value) that makes
+// getctx read the path list from standard input.
+const stdinPath = "-"
+
 type flagConfig struct {
-	outputFilename string
-	logOutput      io.Writer
-	logLevel       logger.Level
-	startPath      string
+	command string
+	// configAction, initUser and initForce belong to the config command.
+	configAction string
+	initUser     bool
+	initForce    bool
+	logOutput    io.Writer
+	logLevel     logger.Level
+	// overrides holds the settings given explicitly on the command line,
+	// the highest-precedence configuration layer.
+	overrides *config.File
+	// profile names the [profiles.<name>] entry to apply, if any.
+	profile string
+	// importList and exportList are selection lists to start from and to
+	// write the final selection to.
+	importList string
+	exportList string
+	// reportFile and verbose ask for the per-file build report.
+	reportFile string
+	verbose    bool
+	startPath  string
+	paths      []string
+	pathLists  []string
 }
 
 type cleanupFunc func()
@@ -23,26 +62,167 @@ var noOpCleanup = func() {}
 
 // TODO: handle --version flag ( version should be set automatically durning release )
 func setupAndParseFlags() (*flagConfig, cleanupFunc, error) {
+	args := os.Args[1:]
+	command := commandInteractive
+	if len(args) > 0 && slices.Contains([]string{commandBuild, commandConfig, commandWhy}, args[0]) {
+		command = args[0]
+		args = args[1:]
+	}
+
+	configAction := ""
+	if command == commandConfig {
+		if len(args) == 0 || !slices.Contains([]string{configShow, configInit, configValidate}, args[0]) {
+			return nil, nil, fmt.Errorf("%w: the %s command needs one of: %s, %s, %s", ErrUsage, commandConfig, configShow, configInit, configValidate)
+		}
+		configAction = args[0]
+		args = args[1:]
+	}
+
 	fs := flag.NewFlagSet("getctx", flag.ExitOnError)
+	fs.Usage = func() { printUsage(fs) }
 
 	cpuprofile := fs.String("cpuprofile", "", "write cpu profile to file")
-	outputFilename := fs.String("o", "context.txt", "The name of the output file.")
+	outputFilename := fs.String("o", config.DefaultOutputFilename, "The name of the output file.")
 	debug := fs.Bool("debug", false, "Enable debug level logging.")
+	format := fs.String("format", config.DefaultFormat, "Output format: "+strings.Join(build.SupportedFormats(), ", ")+".")
+	templatePath := fs.String("template", "", "Render the output with a Go text/template file (implies -format template).")
+	xmlDocuments := fs.Bool("xml-documents", false, "Wrap the xml output in a top-level <documents> element.")
+	tokenizerVocab := fs.String("tokenizer-vocab", "", "Count tokens with a tiktoken-format BPE vocabulary (e.g. cl100k_base.tiktoken) instead of the built-in estimate.")
+	maxTokens := fs.Int("max-tokens", 0, "Maximum number of tokens of file content in the context (0 disables the limit).")
+	tokenBudget := fs.String("token-budget", config.DefaultTokenBudget, "What to do when -max-tokens is exceeded: fail, or drop the lowest-priority files.")
+	tree := fs.Bool("tree", false, "Prepend a tree map of the selection's common ancestor directory (not available for json/jsonl).")
+	treeDepth := fs.Int("tree-depth", config.DefaultTreeDepth, "Maximum depth of the tree map (0 for unlimited).")
+	treeMaxEntries := fs.Int("tree-max-entries", config.DefaultTreeEntries, "Maximum number of entries listed per directory in the tree map (0 for unlimited).")
+	root := fs.String("root", "", "Directory that paths in the output are written relative to (default: nearest directory with .git or go.mod).")
+	noGitignore := fs.Bool("no-gitignore", false, "Do not apply .gitignore, .git/info/exclude and the global git excludes file.")
+	noSession := fs.Bool("no-session", false, "Neither offer the previous selection of the file browser nor remember this one.")
+	jobs := fs.Int("jobs", 0, "How many files to discover, sniff and read at once (0 for one per CPU).")
+	noDefaultExcludes := fs.Bool("no-default-excludes", false, "Drop the built-in exclusion rules (.git, node_modules, vendor, images, archives, ...).")
+	var maxFileSize config.ByteSize
+	fs.Var(&maxFileSize, "max-file-size", "Skip files larger than this size, e.g. 512KB or 2MB (0 for no limit).")
+	var excludes []string
+	fs.Func("exclude", "Exclude paths matching a gitignore-style pattern relative to the project root, e.g. '*.min.js' or 'docs/generated/' (repeatable; '!pattern' re-includes).", func(pattern string) error {
+		excludes = append(excludes, pattern)
+		return nil
+	})
+	var initUser, initForce *bool
+	if configAction == configInit {
+		initUser = fs.Bool("user", false, "Write the user configuration file instead of ./"+config.ProjectConfigFile+".")
+		initForce = fs.Bool("force", false, "Overwrite an existing configuration file.")
+	}
+	profile := fs.String("profile", "", "Apply a profile from the configuration files: its paths, include/exclude patterns, format and output file.")
+	fromFile := fs.String("from-file", "", "Read the paths to include from a newline- or NUL-separated list or a JSON selection list ('-' for stdin). Implies build.")
+	importList := fs.String("import", "", "Start from a selection list exported earlier: preselect it in the file browser, or build it with the build command.")
+	reportFile := fs.String("report", "", "Write a JSON report of every path the build looked at: its decision, size, tokens and the time spent.")
+	verbose := fs.Bool("verbose", false, "Print the per-file build report as a table.")
+	exportList := fs.String("export", "", "Write the final selection to a list file, JSON if it ends in .json and a plain relative path list otherwise.")
 
-	if err := fs.Parse(os.Args[1:]); err != nil {
+	if err := fs.Parse(args); err != nil {
 		return nil, nil, fmt.Errorf("could not parse flags: %w", err)
 	}
 
+	gitignore := !*noGitignore
+	session := !*noSession
+	overrides := &config.File{Exclude: excludes}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "o":
+			overrides.Output = outputFilename
+		case "format":
+			overrides.Format = format
+		case "xml-documents":
+			overrides.XMLDocuments = xmlDocuments
+		case "template":
+			overrides.Template = templatePath
+		case "tokenizer-vocab":
+			overrides.TokenizerVocab = tokenizerVocab
+		case "max-tokens":
+			overrides.MaxTokens = maxTokens
+		case "token-budget":
+			overrides.TokenBudget = tokenBudget
+		case "tree":
+			overrides.Tree = tree
+		case "tree-depth":
+			overrides.TreeDepth = treeDepth
+		case "tree-max-entries":
+			overrides.TreeMaxEntries = treeMaxEntries
+		case "root":
+			overrides.Root = root
+		case "no-gitignore":
+			overrides.Gitignore = &gitignore
+		case "no-session":
+			overrides.Session = &session
+		case "no-default-excludes":
+			overrides.NoDefaultExcludes = noDefaultExcludes
+		case "jobs":
+			overrides.Jobs = jobs
+		case "max-file-size":
+			overrides.MaxFileSize = &maxFileSize
+		}
+	})
+
 	config := &flagConfig{
-		outputFilename: *outputFilename,
-		logOutput:      io.Discard,
-		logLevel:       logger.LevelInfo,
+		command:      command,
+		configAction: configAction,
+		overrides:    overrides,
+		profile:      *profile,
+		importList:   *importList,
+		exportList:   *exportList,
+		reportFile:   *reportFile,
+		verbose:      *verbose,
+		logOutput:    io.Discard,
+		logLevel:     logger.LevelInfo,
+	}
+	if initUser != nil {
+		config.initUser = *initUser
+		config.initForce = *initForce
 	}
 
-	if fs.NArg() > 0 {
-		config.startPath = fs.Arg(0)
-	} else {
+	if *fromFile != "" {
+		config.pathLists = append(config.pathLists, *fromFile)
+	}
+	for _, arg := range fs.Args() {
+		if arg == stdinPath {
+			if !slices.Contains(config.pathLists, stdinPath) {
+				config.pathLists = append(config.pathLists, stdinPath)
+			}
+			continue
+		}
+		config.paths = append(config.paths, arg)
+	}
+
+	// A path list can only be consumed by a headless build, the TUI has no use for it.
+	if len(config.pathLists) > 0 && config.command != commandConfig && config.command != commandWhy {
+		config.command = commandBuild
+	}
+
+	switch config.command {
+	case commandBuild:
+		if len(config.paths) == 0 && len(config.pathLists) == 0 && config.profile == "" && config.importList == "" {
+			return nil, nil, fmt.Errorf("%w: the %s command requires at least one path, a profile or an imported list", ErrUsage, commandBuild)
+		}
+		// Project rule files are looked up from the working directory.
+		config.startPath = "."
+	case commandConfig:
+		if len(config.pathLists) > 0 || len(config.paths) > 1 || configAction == configInit && len(config.paths) > 0 {
+			return nil, nil, fmt.Errorf("%w: too many arguments for %s %s", ErrUsage, commandConfig, configAction)
+		}
+		config.startPath = "."
+		if len(config.paths) == 1 {
+			config.startPath = config.paths[0]
+		}
+	case commandWhy:
+		if len(config.paths) != 1 || len(config.pathLists) > 0 {
+			return nil, nil, fmt.Errorf("%w: the %s command takes exactly one path", ErrUsage, commandWhy)
+		}
+		// Rules are looked up from the working directory, as for build.
 		config.startPath = "."
+	default:
+		if fs.NArg() > 0 {
+			config.startPath = fs.Arg(0)
+		} else {
+			config.startPath = "."
+		}
 	}
 
 	cleanup := noOpCleanup
@@ -81,3 +261,28 @@ func setupAndParseFlags() (*flagConfig, cleanupFunc, error) {
 	return config, cleanup, nil
 
 }
+
+func printUsage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintln(out, "  getctx [flags] [start path]       browse and select files interactively")
+	fmt.Fprintln(out, "  getctx build [flags] <paths...>   build the context without the TUI")
+	fmt.Fprintln(out, "  getctx build -profile <name>      build the paths of a profile from the configuration files")
+	fmt.Fprintln(out, "  <command> | getctx [flags] -      build the context from a path list on stdin")
+	fmt.Fprintln(out, "  getctx -import <list> [start path] browse with a shared selection list preselected")
+	fmt.Fprintln(out, "  getctx config show [flags] [dir]  print the effective configuration and where each value comes from")
+	fmt.Fprintln(out, "  getctx config init [-user]        write a commented starter configuration file")
+	fmt.Fprintln(out, "  getctx config validate [dir]      check configuration files and rules for mistakes")
+	fmt.Fprintln(out, "  getctx why [flags] <path>         explain why a path would be included or left out")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Exit codes:")
+	fmt.Fprintf(out, "  %d  success\n", ExitOK)
+	fmt.Fprintf(out, "  %d  fatal error\n", ExitFatal)
+	fmt.Fprintf(out, "  %d  invalid usage\n", ExitUsage)
+	fmt.Fprintf(out, "  %d  nothing was included in the context\n", ExitNothingIncluded)
+	fmt.Fprintf(out, "  %d  the context was written, but some paths failed\n", ExitPartial)
+	fmt.Fprintf(out, "  %d  why: the path would be left out\n", ExitPathExcluded)
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kacperzielinskidev/getctx/internal/build"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+)
+
+// reportBuild prints the per-file report with -verbose and writes it as JSON
+// to the file given by -report.
+func reportBuild(fsys fs.FileSystem, cfg *flagConfig, result *build.BuildResult) error {
+	if result.Report == nil {
+		return nil
+	}
+	if cfg.verbose {
+		if err := printReport(result.Report); err != nil {
+			return err
+		}
+	}
+	if cfg.reportFile == "" {
+		return nil
+	}
+
+	file, err := fs.CreateAtomic(fsys, cfg.reportFile)
+	if err != nil {
+		return fmt.Errorf("could not create report %s: %w", cfg.reportFile, err)
+	}
+	defer file.Abort()
+	if err := result.Report.WriteJSON(file); err != nil {
+		return fmt.Errorf("could not write report %s: %w", cfg.reportFile, err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("could not write report %s: %w", cfg.reportFile, err)
+	}
+	return nil
+}
+
+// reportFailedBuild still writes the report of a build that failed, which is
+// when it is needed most, and returns the build error.
+func reportFailedBuild(fsys fs.FileSystem, cfg *flagConfig, result *build.BuildResult, buildErr error) error {
+	if result == nil {
+		return buildErr
+	}
+	if err := reportBuild(fsys, cfg, result); err != nil {
+		return errors.Join(buildErr, err)
+	}
+	return buildErr
+}
+
+func printReport(report *build.Report) error {
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "DECISION\tSIZE\tWRITTEN\tTOKENS\tTIME\tPATH\tDETAIL")
+	for _, entry := range report.Files {
+		path := entry.Path
+		if entry.Dir {
+			path += "/"
+		}
+		tokens := "-"
+		if entry.Tokens > 0 {
+			tokens = fmt.Sprint(entry.Tokens)
+		}
+		fmt.Fprintf(out, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n", entry.Decision, entry.Size, entry.Written, tokens, entry.Duration.Round(time.Microsecond), path, entry.Detail)
+	}
+	totals := report.Totals
+	fmt.Fprintf(out, "\n%d path(s): %d included (%d bytes, %d tokens), %d skipped, %d failed in %s\n\n",
+		totals.Files, totals.Included, totals.Bytes, totals.Tokens, totals.Skipped, totals.Failed, report.Duration.Round(time.Millisecond))
+	return out.Flush()
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kacperzielinskidev/getctx/internal/build"
+	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/tui"
+)
+
+const (
+	sourceEnv   = "environment"
+	sourceFlags = "command line"
+)
+
+// loadConfig merges the configuration layers in order of precedence: the
+// built-in defaults, the user file, the project .getctx.toml, GETCTX_*
+// environment variables, the profile chosen with -profile and finally the
+// flags given on the command line. Every profile is validated, so one picked
+// later in the TUI is known to be usable.
+func loadConfig(fsys fs.FileSystem, cfg *flagConfig) (*config.Config, error) {
+	appConfig := config.NewConfig()
+
+	for _, path := range configFiles(fsys, cfg.startPath) {
+		file, err := config.LoadFile(fsys, path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid configuration file: %w", ErrUsage, err)
+		}
+		if err := appConfig.Apply(file, path); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+	}
+
+	env, err := config.FromEnv(os.Getenv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	if err := appConfig.Apply(env, sourceEnv); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+
+	if cfg.profile != "" {
+		if appConfig, err = appConfig.WithProfile(cfg.profile, sourceFlags); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+	}
+
+	if err := appConfig.Apply(cfg.overrides, sourceFlags); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	for _, path := range []string{cfg.reportFile, cfg.exportList} {
+		if path != "" {
+			appConfig.GeneratedFiles = append(appConfig.GeneratedFiles, path)
+		}
+	}
+
+	if err := validateConfig(appConfig); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	if err := validateProfiles(appConfig); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	return appConfig, nil
+}
+
+// validateProfiles checks the configuration each profile would produce.
+func validateProfiles(appConfig *config.Config) error {
+	var errs []error
+	for _, name := range appConfig.ProfileNames() {
+		profileConfig, err := appConfig.WithProfile(name, sourceFlags)
+		if err == nil {
+			err = validateConfig(profileConfig)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("profile %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// configFiles returns the user and project configuration files that exist,
+// lowest precedence first.
+func configFiles(fsys fs.FileSystem, startPath string) []string {
+	var paths []string
+	for _, path := range []string{config.UserConfigPath(fsys), config.ProjectConfigPath(fsys, startPath)} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// validateConfig checks the merged configuration, so a bad value is reported
+// the same way whichever layer it came from.
+func validateConfig(appConfig *config.Config) error {
+	if err := build.ValidateFormat(appConfig.Format); err != nil {
+		return err
+	}
+	if appConfig.Format == build.FormatTemplate && appConfig.Template == "" {
+		return fmt.Errorf("the %s format requires a template", build.FormatTemplate)
+	}
+	if appConfig.Format != build.FormatTemplate && appConfig.Template != "" {
+		return fmt.Errorf("a template only applies to the %s format, not %s", build.FormatTemplate, appConfig.Format)
+	}
+
+	if appConfig.OutputFilename == "" {
+		return fmt.Errorf("the output filename must not be empty")
+	}
+	if appConfig.MaxTokens < 0 {
+		return fmt.Errorf("max tokens must not be negative")
+	}
+	if appConfig.TokenBudget != build.BudgetFail && appConfig.TokenBudget != build.BudgetDrop {
+		return fmt.Errorf("token budget must be %q or %q", build.BudgetFail, build.BudgetDrop)
+	}
+
+	if appConfig.TreeMap && (appConfig.Format == build.FormatJSON || appConfig.Format == build.FormatJSONL) {
+		return fmt.Errorf("the tree map is not supported with the %s format", appConfig.Format)
+	}
+	if appConfig.TreeDepth < 0 || appConfig.TreeMaxEntries < 0 {
+		return fmt.Errorf("tree depth and tree max entries must not be negative")
+	}
+
+	if appConfig.Jobs < 0 {
+		return fmt.Errorf("jobs must not be negative")
+	}
+
+	return tui.ValidateKeyBindings(appConfig.KeyBindings)
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kacperzielinskidev/getctx/internal/build"
+)
+
+// explainPath prints every step a build would take for a single path and
+// reports ErrPathExcluded when the path would be left out.
+func explainPath(contextBuilder *build.ContextBuilder, path string) error {
+	explanation := contextBuilder.Explain(path)
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(out, "🔍 %s\n", explanation.Path)
+	for _, step := range explanation.Steps {
+		mark := "✔"
+		if !step.Passed {
+			mark = "✘"
+		}
+		fmt.Fprintf(out, "  %s %s\t%s\n", mark, step.Name, step.Detail)
+	}
+	fmt.Fprintln(out)
+	if explanation.Included {
+		fmt.Fprintln(out, "✅ Included in the context.")
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	if !explanation.Included {
+		return ErrPathExcluded
+	}
+	return nil
+}
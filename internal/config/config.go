@@ -1,15 +1,70 @@
 package config
 
 import (
-	"path/filepath"
-	"strings"
+	"fmt"
+
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
+)
+
+const (
+	DefaultOutputFilename = "context.txt"
+	DefaultFormat         = "plain"
+	DefaultTokenBudget    = "fail"
+	DefaultTreeDepth      = 4
+	DefaultTreeEntries    = 25
 )
 
+// Theme overrides TUI colors. Values are ANSI color numbers ("34") or hex
+// colors ("#00ff00"); empty values keep the built-in color.
+type Theme struct {
+	Selected string `json:"selected,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
 type Config struct {
-	ExcludedNames      map[string]struct{}
-	ExcludedExtensions map[string]struct{}
+	// Exclude holds the exclusion patterns in gitignore syntax: the built-in
+	// defaults followed by the ones added with AddExclude. They are matched
+	// against paths relative to Exclude.Base, the project root.
+	Exclude             *ignore.Rules
+	NoDefaultExcludes   bool
+	OutputFilename      string
+	Format              string
+	XMLDocumentsWrapper bool
+	Template            string
+	TokenizerVocab      string
+	MaxTokens           int
+	TokenBudget         string
+	TreeMap             bool
+	TreeDepth           int
+	TreeMaxEntries      int
+	Root                string
+	RespectGitignore    bool
+	MaxFileSize         int64
+	Jobs                int
+	Session             bool
+	Theme               Theme
+	// KeyBindings maps TUI actions to the keys that trigger them; actions
+	// not listed keep their default keys.
+	KeyBindings map[string][]string
+	// Origins records which layer last set each setting, keyed by its name
+	// in configuration files. Settings that were never overridden are absent.
+	Origins map[string]string
+	// IgnoreRules and IncludeRules hold the .getctxignore and .getctxinclude
+	// files found above the start path, outermost first.
+	IgnoreRules  []*ignore.Rules
+	IncludeRules []*ignore.Rules
+	// Profiles are the named selections from the configuration files.
+	// Profile is the one in use, if any, and ProfileInclude its allowlist.
+	Profiles       map[string]Profile
+	Profile        string
+	ProfileInclude *ignore.Rules
+	// GeneratedFiles are the other files a run writes, the report and an
+	// exported list. Like the output file, they are never read in.
+	GeneratedFiles []string
 }
 
+// defaultExcludedNames match files and directories with these names anywhere.
 var defaultExcludedNames = []string{
 	".git", ".svn", ".hg",
 	"node_modules", "vendor",
@@ -22,6 +77,7 @@ var defaultExcludedNames = []string{
 	"context.txt",
 }
 
+// defaultExcludedExtensions are matched case-insensitively.
 var defaultExcludedExtensions = []string{
 	".jpg", ".jpeg", ".jpe", ".png", ".gif", ".bmp", ".tiff", ".tif", ".webp",
 	".ico", ".heic", ".heif", ".avif", ".jp2", ".j2k", ".jpf", ".jpx", ".jpm",
@@ -34,36 +90,168 @@ var defaultExcludedExtensions = []string{
 	".dmg", ".jar", ".war", ".ear",
 }
 
+const defaultsSource = "built-in"
+
 func NewConfig() *Config {
 	cfg := &Config{
-		ExcludedNames:      make(map[string]struct{}),
-		ExcludedExtensions: make(map[string]struct{}),
+		Exclude:          &ignore.Rules{Unbounded: true},
+		OutputFilename:   DefaultOutputFilename,
+		Format:           DefaultFormat,
+		TokenBudget:      DefaultTokenBudget,
+		TreeDepth:        DefaultTreeDepth,
+		TreeMaxEntries:   DefaultTreeEntries,
+		RespectGitignore: true,
+		Session:          true,
+		KeyBindings:      make(map[string][]string),
+		Origins:          make(map[string]string),
+		Profiles:         make(map[string]Profile),
 	}
+	cfg.Exclude.Patterns = defaultExcludes()
 
+	return cfg
+}
+
+func defaultExcludes() []*ignore.Pattern {
+	patterns := make([]*ignore.Pattern, 0, len(defaultExcludedNames)+len(defaultExcludedExtensions))
 	for _, name := range defaultExcludedNames {
-		cfg.ExcludedNames[name] = struct{}{}
+		patterns = append(patterns, mustParseDefault(name))
 	}
-
 	for _, ext := range defaultExcludedExtensions {
-		cfg.ExcludedExtensions[ext] = struct{}{}
+		patterns = append(patterns, mustParseDefault("*"+ext).FoldCase())
 	}
+	return patterns
+}
 
-	return cfg
+func mustParseDefault(line string) *ignore.Pattern {
+	pattern, err := ignore.ParsePattern(line, defaultsSource, 0)
+	if err != nil || pattern == nil {
+		panic(fmt.Sprintf("invalid built-in exclusion %q: %v", line, err))
+	}
+	return pattern
+}
+
+// AddExclude appends an exclusion pattern. Later patterns take precedence, so
+// "!vendor" re-includes a directory excluded by default.
+func (c *Config) AddExclude(line, source string) error {
+	pattern, err := ignore.ParsePattern(line, source, 0)
+	if err != nil {
+		return err
+	}
+	if pattern == nil {
+		return fmt.Errorf("%s: empty exclusion pattern", source)
+	}
+	c.Exclude.Patterns = append(c.Exclude.Patterns, pattern)
+	return nil
+}
+
+// Apply overlays a configuration layer and records source as the origin of
+// every setting it contains. Exclusion patterns accumulate, so a later layer
+// can re-include what an earlier one excluded with "!pattern"; a profile
+// replaces one of the same name from an earlier layer.
+func (c *Config) Apply(file *File, source string) error {
+	set(c, source, "output", &c.OutputFilename, file.Output)
+	applyFormat(c, source, file.Format, file.Template)
+	set(c, source, "xml_documents", &c.XMLDocumentsWrapper, file.XMLDocuments)
+	set(c, source, "tokenizer_vocab", &c.TokenizerVocab, file.TokenizerVocab)
+	set(c, source, "max_tokens", &c.MaxTokens, file.MaxTokens)
+	set(c, source, "token_budget", &c.TokenBudget, file.TokenBudget)
+	set(c, source, "tree", &c.TreeMap, file.Tree)
+	set(c, source, "tree_depth", &c.TreeDepth, file.TreeDepth)
+	set(c, source, "tree_max_entries", &c.TreeMaxEntries, file.TreeMaxEntries)
+	set(c, source, "root", &c.Root, file.Root)
+	set(c, source, "gitignore", &c.RespectGitignore, file.Gitignore)
+	set(c, source, "session", &c.Session, file.Session)
+	set(c, source, "jobs", &c.Jobs, file.Jobs)
+	if file.MaxFileSize != nil {
+		maxFileSize := int64(*file.MaxFileSize)
+		set(c, source, "max_file_size", &c.MaxFileSize, &maxFileSize)
+	}
+
+	if file.NoDefaultExcludes != nil {
+		c.setDefaultExcludes(!*file.NoDefaultExcludes)
+		c.Origins["no_default_excludes"] = source
+	}
+	for _, pattern := range file.Exclude {
+		if err := c.AddExclude(pattern, source); err != nil {
+			return err
+		}
+	}
+
+	if file.Theme != nil {
+		setNonEmpty(c, source, "theme.selected", &c.Theme.Selected, file.Theme.Selected)
+		setNonEmpty(c, source, "theme.hint", &c.Theme.Hint, file.Theme.Hint)
+		setNonEmpty(c, source, "theme.error", &c.Theme.Error, file.Theme.Error)
+	}
+	for action, keys := range file.Keys {
+		c.KeyBindings[action] = keys
+		c.Origins["keys."+action] = source
+	}
+	for name, profile := range file.Profiles {
+		c.Profiles[name] = profile
+		c.Origins["profiles."+name] = source
+	}
+
+	return nil
+}
+
+// templateFormat is the name of the format that renders a template.
+const templateFormat = "template"
+
+// applyFormat sets the format and template of one layer, which decides both:
+// a template without a format implies the template format, and another
+// format without a template drops the template of an earlier layer. A layer
+// that gives both keeps both, and validation rejects a mismatch.
+func applyFormat(c *Config, source string, format, template *string) {
+	if template != nil && *template != "" && format == nil {
+		implied := templateFormat
+		format = &implied
+	}
+	if format != nil && *format != templateFormat && template == nil && c.Template != "" {
+		dropped := ""
+		template = &dropped
+	}
+	set(c, source, "format", &c.Format, format)
+	set(c, source, "template", &c.Template, template)
+}
+
+// Origin returns the layer that set a setting, or the built-in default.
+func (c *Config) Origin(key string) string {
+	if origin, ok := c.Origins[key]; ok {
+		return origin
+	}
+	return defaultsSource
 }
 
-func (c *Config) IsExcluded(name string) bool {
-	if _, found := c.ExcludedNames[name]; found {
-		return true
+// setDefaultExcludes removes or restores the built-in exclusion patterns,
+// keeping every pattern added by a configuration layer.
+func (c *Config) setDefaultExcludes(enabled bool) {
+	if enabled == !c.NoDefaultExcludes {
+		return
 	}
+	c.NoDefaultExcludes = !enabled
 
-	ext := filepath.Ext(name)
-	if ext == "" {
-		return false
+	var added []*ignore.Pattern
+	for _, pattern := range c.Exclude.Patterns {
+		if pattern.Source != defaultsSource {
+			added = append(added, pattern)
+		}
+	}
+	if enabled {
+		added = append(defaultExcludes(), added...)
 	}
+	c.Exclude.Patterns = added
+}
 
-	if _, found := c.ExcludedExtensions[strings.ToLower(ext)]; found {
-		return true
+func set[T any](c *Config, source, key string, target *T, value *T) {
+	if value != nil {
+		*target = *value
+		c.Origins[key] = source
 	}
+}
 
-	return false
+func setNonEmpty(c *Config, source, key string, target *string, value string) {
+	if value != "" {
+		*target = value
+		c.Origins[key] = source
+	}
 }
@@ -0,0 +1,73 @@
+package config
+
+import "testing"
+
+// TestApplyFormatAndTemplate checks that each layer decides the format and
+// the template together, so a later layer wins over both.
+func TestApplyFormatAndTemplate(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name         string
+		layers       []*File
+		profile      *Profile
+		wantFormat   string
+		wantTemplate string
+	}{
+		{
+			name:         "template implies its format",
+			layers:       []*File{{Template: str("t.tmpl")}},
+			wantFormat:   templateFormat,
+			wantTemplate: "t.tmpl",
+		},
+		{
+			name:       "a later format drops the template",
+			layers:     []*File{{Template: str("t.tmpl")}, {Format: str("json")}},
+			wantFormat: "json",
+		},
+		{
+			name:         "a later template wins over a format",
+			layers:       []*File{{Format: str("json")}, {Template: str("t.tmpl")}},
+			wantFormat:   templateFormat,
+			wantTemplate: "t.tmpl",
+		},
+		{
+			name:         "a later template format keeps the template",
+			layers:       []*File{{Template: str("t.tmpl")}, {Format: str(templateFormat)}},
+			wantFormat:   templateFormat,
+			wantTemplate: "t.tmpl",
+		},
+		{
+			name:         "one layer with both keeps both",
+			layers:       []*File{{Format: str("json"), Template: str("t.tmpl")}},
+			wantFormat:   "json",
+			wantTemplate: "t.tmpl",
+		},
+		{
+			name:       "a profile format drops the template",
+			layers:     []*File{{Template: str("t.tmpl")}},
+			profile:    &Profile{Format: str("markdown")},
+			wantFormat: "markdown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			for i, layer := range tt.layers {
+				if err := cfg.Apply(layer, string(rune('a'+i))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.profile != nil {
+				cfg.Profiles["p"] = *tt.profile
+				var err error
+				if cfg, err = cfg.WithProfile("p", "flags"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if cfg.Format != tt.wantFormat || cfg.Template != tt.wantTemplate {
+				t.Errorf("format %q, template %q; want %q, %q", cfg.Format, cfg.Template, tt.wantFormat, tt.wantTemplate)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	appName            = "getctx"
+	ProjectConfigFile  = ".getctx.toml"
+	UserConfigFile     = "config.toml"
+	userConfigBaseName = "config"
+	envPrefix          = "GETCTX_"
+)
+
+// FileSystem is the part of fs.FileSystem needed to find and read
+// configuration files.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	Stat(name string) (iofs.FileInfo, error)
+	Abs(path string) (string, error)
+	UserHomeDir() (string, error)
+}
+
+// File is one configuration layer. Every field is optional; only the ones
+// that are set override the layers below.
+type File struct {
+	Output            *string            `json:"output,omitempty"`
+	Format            *string            `json:"format,omitempty"`
+	XMLDocuments      *bool              `json:"xml_documents,omitempty"`
+	Template          *string            `json:"template,omitempty"`
+	TokenizerVocab    *string            `json:"tokenizer_vocab,omitempty"`
+	MaxTokens         *int               `json:"max_tokens,omitempty"`
+	TokenBudget       *string            `json:"token_budget,omitempty"`
+	Tree              *bool              `json:"tree,omitempty"`
+	TreeDepth         *int               `json:"tree_depth,omitempty"`
+	TreeMaxEntries    *int               `json:"tree_max_entries,omitempty"`
+	Root              *string            `json:"root,omitempty"`
+	Gitignore         *bool              `json:"gitignore,omitempty"`
+	NoDefaultExcludes *bool              `json:"no_default_excludes,omitempty"`
+	Exclude           []string           `json:"exclude,omitempty"`
+	MaxFileSize       *ByteSize          `json:"max_file_size,omitempty"`
+	Jobs              *int               `json:"jobs,omitempty"`
+	Session           *bool              `json:"session,omitempty"`
+	Theme             *Theme             `json:"theme,omitempty"`
+	Keys              map[string]KeyList `json:"keys,omitempty"`
+	Profiles          map[string]Profile `json:"profiles,omitempty"`
+}
+
+// KeyList is a list of key names that also accepts a single string.
+type KeyList []string
+
+func (k *KeyList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*k = KeyList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("expected a key name or a list of key names")
+	}
+	*k = list
+	return nil
+}
+
+// ParseFile decodes a configuration file. Files ending in .json are read as
+// JSON, everything else as TOML. Unknown keys are errors.
+func ParseFile(data []byte, path string) (*File, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		values, err := parseTOML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(values); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	file, err := decodeFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+func decodeFile(data []byte) (*File, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var file File
+	if err := decoder.Decode(&file); err != nil {
+		return nil, describeDecodeError(err)
+	}
+	return &file, nil
+}
+
+// describeDecodeError rewrites encoding/json errors in terms of the
+// configuration file rather than Go types.
+func describeDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("invalid value for %q: expected %s, got %s", typeErr.Field, describeType(typeErr.Type.Kind()), typeErr.Value)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Errorf("unknown key %s", field)
+	}
+	return err
+}
+
+func describeType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Map, reflect.Struct:
+		return "a table"
+	default:
+		return kind.String()
+	}
+}
+
+// LoadFile reads and decodes the configuration file at path. Relative paths
+// inside it are resolved against the directory of the file.
+func LoadFile(fsys FileSystem, path string) (*File, error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ParseFile(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	values := []*string{file.Template, file.TokenizerVocab, file.Root}
+	for _, profile := range file.Profiles {
+		for i := range profile.Paths {
+			values = append(values, &profile.Paths[i])
+		}
+	}
+	for _, value := range values {
+		if value != nil && *value != "" && !filepath.IsAbs(*value) {
+			*value = filepath.Join(dir, *value)
+		}
+	}
+	return file, nil
+}
+
+// UserConfigPath returns the user configuration file,
+// $XDG_CONFIG_HOME/getctx/config.toml or config.json, or "" if neither exists.
+func UserConfigPath(fsys FileSystem) string {
+	dir := UserConfigDir(fsys)
+	if dir == "" {
+		return ""
+	}
+	for _, ext := range []string{".toml", ".json"} {
+		path := filepath.Join(dir, userConfigBaseName+ext)
+		if _, err := fsys.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// UserConfigDir returns $XDG_CONFIG_HOME/getctx, falling back to
+// ~/.config/getctx.
+func UserConfigDir(fsys FileSystem) string {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, appName)
+	}
+	home, err := fsys.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".config", appName)
+}
+
+// ProjectConfigPath returns the nearest .getctx.toml in startPath or one of
+// its parents, up to the repository root, or "" if there is none.
+func ProjectConfigPath(fsys FileSystem, startPath string) string {
+	dir, err := fsys.Abs(startPath)
+	if err != nil {
+		return ""
+	}
+	if info, err := fsys.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		if _, err := fsys.Stat(path); err == nil {
+			return path
+		}
+		if _, err := fsys.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// FromEnv builds a layer from the GETCTX_* environment variables, e.g.
+// GETCTX_FORMAT=markdown or GETCTX_EXCLUDE='*.min.js,docs/generated/'.
+func FromEnv(getenv func(string) string) (*File, error) {
+	var file File
+	var errs []error
+
+	str := func(name string) *string {
+		if value := getenv(envPrefix + name); value != "" {
+			return &value
+		}
+		return nil
+	}
+	boolean := func(name string) *bool {
+		value := str(name)
+		if value == nil {
+			return nil
+		}
+		parsed, err := strconv.ParseBool(*value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s%s: expected true or false, got %q", envPrefix, name, *value))
+			return nil
+		}
+		return &parsed
+	}
+	integer := func(name string) *int {
+		value := str(name)
+		if value == nil {
+			return nil
+		}
+		parsed, err := strconv.Atoi(*value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s%s: expected a number, got %q", envPrefix, name, *value))
+			return nil
+		}
+		return &parsed
+	}
+
+	file.Output = str("OUTPUT")
+	file.Format = str("FORMAT")
+	file.XMLDocuments = boolean("XML_DOCUMENTS")
+	file.Template = str("TEMPLATE")
+	file.TokenizerVocab = str("TOKENIZER_VOCAB")
+	file.MaxTokens = integer("MAX_TOKENS")
+	file.TokenBudget = str("TOKEN_BUDGET")
+	file.Tree = boolean("TREE")
+	file.TreeDepth = integer("TREE_DEPTH")
+	file.TreeMaxEntries = integer("TREE_MAX_ENTRIES")
+	file.Root = str("ROOT")
+	file.Gitignore = boolean("GITIGNORE")
+	file.NoDefaultExcludes = boolean("NO_DEFAULT_EXCLUDES")
+	file.Session = boolean("SESSION")
+	file.Jobs = integer("JOBS")
+	if value := str("EXCLUDE"); value != nil {
+		for _, pattern := range strings.Split(*value, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				file.Exclude = append(file.Exclude, pattern)
+			}
+		}
+	}
+	if value := str("MAX_FILE_SIZE"); value != nil {
+		size, err := ParseByteSize(*value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sMAX_FILE_SIZE: %w", envPrefix, err))
+		} else {
+			file.MaxFileSize = &size
+		}
+	}
+
+	return &file, errors.Join(errs...)
+}
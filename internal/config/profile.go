@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
+)
+
+// Profile is a named selection defined under [profiles.<name>]: the paths to
+// build, extra include and exclude patterns, and the format and output file.
+type Profile struct {
+	Description string   `json:"description,omitempty"`
+	Paths       []string `json:"paths,omitempty"`
+	Include     []string `json:"include,omitempty"`
+	Exclude     []string `json:"exclude,omitempty"`
+	Format      *string  `json:"format,omitempty"`
+	Output      *string  `json:"output,omitempty"`
+}
+
+// ProfileNames returns the names of the defined profiles, sorted.
+func (c *Config) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(c.Profiles))
+}
+
+const profileSourcePrefix = "profile "
+
+// WithProfile returns a copy of the configuration with the named profile
+// applied on top of it, replacing the patterns of a profile applied before.
+// Its include patterns become an allowlist anchored at the project root, like
+// a .getctxinclude file there. source is recorded as the origin of the
+// profile choice.
+func (c *Config) WithProfile(name, source string) (*Config, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile %q (no profiles are defined)", name)
+		}
+		return nil, fmt.Errorf("unknown profile %q (defined profiles: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	cfg := c.clone()
+	cfg.Exclude.Patterns = slices.DeleteFunc(cfg.Exclude.Patterns, func(pattern *ignore.Pattern) bool {
+		return strings.HasPrefix(pattern.Source, profileSourcePrefix)
+	})
+	profileSource := profileSourcePrefix + name
+	applyFormat(cfg, profileSource, profile.Format, nil)
+	set(cfg, profileSource, "output", &cfg.OutputFilename, profile.Output)
+	for _, line := range profile.Exclude {
+		if err := cfg.AddExclude(line, profileSource); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.ProfileInclude = nil
+	if len(profile.Include) > 0 {
+		rules := &ignore.Rules{Base: c.Exclude.Base}
+		for _, line := range profile.Include {
+			pattern, err := ignore.ParsePattern(line, profileSource, 0)
+			if err != nil {
+				return nil, err
+			}
+			if pattern == nil {
+				return nil, fmt.Errorf("%s: empty include pattern", profileSource)
+			}
+			rules.Patterns = append(rules.Patterns, pattern)
+		}
+		cfg.ProfileInclude = rules
+	}
+
+	cfg.Profile = name
+	cfg.Origins["profile"] = source
+	return cfg, nil
+}
+
+// ProfilePaths returns the paths a profile builds, or nil if it lists none.
+func (c *Config) ProfilePaths() []string {
+	return c.Profiles[c.Profile].Paths
+}
+
+func (c *Config) clone() *Config {
+	cfg := *c
+	cfg.Exclude = c.Exclude.Clone()
+	cfg.Origins = maps.Clone(c.Origins)
+	return &cfg
+}
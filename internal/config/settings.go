@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Setting is one effective configuration value and the layer it came from.
+type Setting struct {
+	Key    string
+	Value  string
+	Origin string
+}
+
+// Settings lists every setting in the order of the configuration file, with
+// values written the way a configuration file would spell them.
+func (c *Config) Settings() []Setting {
+	quote := strconv.Quote
+	settings := []Setting{
+		{Key: "output", Value: quote(c.OutputFilename)},
+		{Key: "format", Value: quote(c.Format)},
+		{Key: "xml_documents", Value: strconv.FormatBool(c.XMLDocumentsWrapper)},
+		{Key: "template", Value: quote(c.Template)},
+		{Key: "tokenizer_vocab", Value: quote(c.TokenizerVocab)},
+		{Key: "max_tokens", Value: strconv.Itoa(c.MaxTokens)},
+		{Key: "token_budget", Value: quote(c.TokenBudget)},
+		{Key: "tree", Value: strconv.FormatBool(c.TreeMap)},
+		{Key: "tree_depth", Value: strconv.Itoa(c.TreeDepth)},
+		{Key: "tree_max_entries", Value: strconv.Itoa(c.TreeMaxEntries)},
+		{Key: "root", Value: quote(c.Root)},
+		{Key: "gitignore", Value: strconv.FormatBool(c.RespectGitignore)},
+		{Key: "max_file_size", Value: strconv.FormatInt(c.MaxFileSize, 10)},
+		{Key: "no_default_excludes", Value: strconv.FormatBool(c.NoDefaultExcludes)},
+		{Key: "jobs", Value: strconv.Itoa(c.Jobs)},
+		{Key: "session", Value: strconv.FormatBool(c.Session)},
+		{Key: "theme.selected", Value: quote(c.Theme.Selected)},
+		{Key: "theme.hint", Value: quote(c.Theme.Hint)},
+		{Key: "theme.error", Value: quote(c.Theme.Error)},
+		{Key: "profile", Value: quote(c.Profile)},
+	}
+
+	actions := make([]string, 0, len(c.KeyBindings))
+	for action := range c.KeyBindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		keys := make([]string, len(c.KeyBindings[action]))
+		for i, key := range c.KeyBindings[action] {
+			keys[i] = quote(key)
+		}
+		settings = append(settings, Setting{
+			Key:   "keys." + action,
+			Value: fmt.Sprintf("[%s]", strings.Join(keys, ", ")),
+		})
+	}
+
+	for i := range settings {
+		settings[i].Origin = c.Origin(settings[i].Key)
+	}
+	return settings
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a size in bytes. In configuration files it is written either
+// as a plain number or with a binary unit, e.g. "512KB" or "2MB".
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+func ParseByteSize(text string) (ByteSize, error) {
+	value := strings.ToUpper(strings.TrimSpace(text))
+	multiplier := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500000, 512KB or 2MB)", text)
+	}
+	return ByteSize(n * multiplier), nil
+}
+
+func (s *ByteSize) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		if n < 0 {
+			return fmt.Errorf("size must not be negative")
+		}
+		*s = ByteSize(n)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("expected a size such as 512KB")
+	}
+	size, err := ParseByteSize(text)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// Set implements flag.Value.
+func (s *ByteSize) Set(text string) error {
+	size, err := ParseByteSize(text)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+func (s *ByteSize) String() string {
+	if s == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*s), 10)
+}
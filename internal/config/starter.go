@@ -0,0 +1,82 @@
+package config
+
+// StarterFile is written by `getctx config init`. Every setting is commented
+// out, so the file changes nothing until it is edited.
+const StarterFile = `# getctx configuration.
+#
+# Layers are merged in this order, later ones winning: built-in defaults,
+# the user file ($XDG_CONFIG_HOME/getctx/config.toml), the nearest
+# .getctx.toml, GETCTX_* environment variables and command-line flags.
+# Run 'getctx config show' to see the result and 'getctx config validate'
+# to check this file.
+
+# Name of the context file.
+# output = "context.txt"
+
+# plain, markdown, xml, json, jsonl or template.
+# format = "plain"
+# xml_documents = false
+# template = "context.tmpl"
+
+# Token counting and budgets. token_budget is "fail" or "drop".
+# tokenizer_vocab = "cl100k_base.tiktoken"
+# max_tokens = 100000
+# token_budget = "fail"
+
+# Project tree map at the top of the context.
+# tree = false
+# tree_depth = 4
+# tree_max_entries = 25
+
+# Directory that paths in the output are relative to
+# (default: the nearest directory with .git or go.mod).
+# root = "."
+
+# Honor .gitignore, .git/info/exclude and the global git excludes file.
+# gitignore = true
+
+# Skip files larger than this; 0 means no limit.
+# max_file_size = "1MB"
+
+# How many files are discovered, sniffed and read at once; 0 means one per
+# CPU. The output is the same for any value.
+# jobs = 0
+
+# Gitignore-style exclusion patterns relative to the project root.
+# "!pattern" re-includes something excluded earlier.
+# no_default_excludes = false
+# exclude = [
+#   "*.min.js",
+#   "**/mocks/**",
+#   "!vendor/",
+# ]
+
+# Remember the selection, directory and cursor of the file browser per
+# project and offer them back on the next launch.
+# session = true
+
+# Colors: ANSI color numbers or hex values.
+# [theme]
+# selected = "34"
+# hint = "86"
+# error = "9"
+
+# Keys for the file browser: one key or a list per action. Actions: up, down,
+# top, bottom, open, parent, select, select_all, filter, find_path,
+# clear_filter, profiles, export, import, save, quit.
+# [keys]
+# select = ["space", "x"]
+# save = "q"
+
+# Named selections, built with "getctx build -profile api" or picked in the
+# file browser. Paths are relative to this file; without paths a profile
+# builds the whole project. Include patterns work like a .getctxinclude file
+# at the project root.
+# [profiles.api]
+# description = "HTTP handlers and their models"
+# paths = ["internal/api", "internal/models"]
+# include = ["**/*.go"]
+# exclude = ["*_test.go"]
+# format = "markdown"
+# output = "api-context.md"
+`
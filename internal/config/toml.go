@@ -0,0 +1,372 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML reads the subset of TOML that configuration files need: comments,
+// `key = value` pairs with plain or dotted keys, `[table]` and `[table.sub]`
+// headers, basic and literal strings, integers, booleans and (possibly
+// multi-line) arrays of those. The result has the same shape encoding/json
+// produces for an object.
+func parseTOML(data []byte) (map[string]any, error) {
+	p := &tomlParser{src: string(data), line: 1}
+	return p.parse()
+}
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) parse() (map[string]any, error) {
+	root := make(map[string]any)
+	table := root
+	var tableNames []string
+	// defined holds the tables that a header or a dotted key has defined,
+	// which no later header may define again.
+	defined := make(map[string]bool)
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			p.pos++
+			names, err := p.dottedKey()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume(']') {
+				return nil, p.errorf("expected ']' after table name")
+			}
+			name := strings.Join(names, ".")
+			if defined[name] {
+				return nil, p.errorf("table %q defined more than once", name)
+			}
+			defined[name] = true
+			if table, err = p.openTable(root, names); err != nil {
+				return nil, err
+			}
+			tableNames = names
+		} else {
+			names, err := p.dottedKey()
+			if err != nil {
+				return nil, err
+			}
+			name := names[len(names)-1]
+			if !p.consume('=') {
+				return nil, p.errorf("expected '=' after key %q", strings.Join(names, "."))
+			}
+			p.skipSpaces()
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			target := table
+			if len(names) > 1 {
+				parents := append(append([]string(nil), tableNames...), names[:len(names)-1]...)
+				for i := len(tableNames); i < len(parents); i++ {
+					defined[strings.Join(parents[:i+1], ".")] = true
+				}
+				if target, err = p.openTable(table, names[:len(names)-1]); err != nil {
+					return nil, err
+				}
+			}
+			if _, exists := target[name]; exists {
+				return nil, p.errorf("key %q defined more than once", strings.Join(names, "."))
+			}
+			target[name] = value
+		}
+
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) key() (string, error) {
+	p.skipSpaces()
+	if p.eof() {
+		return "", p.errorf("expected a key")
+	}
+	switch p.peek() {
+	case '"':
+		return p.basicString()
+	case '\'':
+		return p.literalString()
+	}
+
+	start := p.pos
+	for !p.eof() && isBareKeyChar(p.peek()) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("unexpected character %q in key", p.peek())
+	}
+	return p.src[start:p.pos], nil
+}
+
+// dottedKey reads a key or table name such as profiles.api,
+// profiles."tui only" or theme.selected.
+func (p *tomlParser) dottedKey() ([]string, error) {
+	var names []string
+	for {
+		name, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		p.skipSpaces()
+		if !p.consume('.') {
+			return names, nil
+		}
+	}
+}
+
+// openTable returns the table at the given path, creating the tables along
+// the way that do not exist yet.
+func (p *tomlParser) openTable(root map[string]any, names []string) (map[string]any, error) {
+	table := root
+	for i, name := range names {
+		existing, exists := table[name]
+		if !exists {
+			next := make(map[string]any)
+			table[name] = next
+			table = next
+			continue
+		}
+		next, ok := existing.(map[string]any)
+		if !ok {
+			return nil, p.errorf("key %q is not a table", strings.Join(names[:i+1], "."))
+		}
+		table = next
+	}
+	return table, nil
+}
+
+func (p *tomlParser) value() (any, error) {
+	if p.eof() {
+		return nil, p.errorf("expected a value")
+	}
+
+	switch c := p.peek(); {
+	case c == '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.basicString()
+	case c == '\'':
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	default:
+		start := p.pos
+		for !p.eof() && !strings.ContainsRune(" \t\r\n,]#", rune(p.peek())) {
+			p.pos++
+		}
+		return parseTOMLScalar(p.src[start:p.pos], p.line)
+	}
+}
+
+// tomlIntegers are the integer forms TOML allows, with the base of each:
+// decimal without leading zeros and with an optional sign, or unsigned
+// hexadecimal, octal and binary. Underscores must sit between digits.
+var tomlIntegers = []struct {
+	pattern *regexp.Regexp
+	prefix  string
+	base    int
+}{
+	{regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`), "", 10},
+	{regexp.MustCompile(`^0x[0-9a-fA-F](_?[0-9a-fA-F])*$`), "0x", 16},
+	{regexp.MustCompile(`^0o[0-7](_?[0-7])*$`), "0o", 8},
+	{regexp.MustCompile(`^0b[01](_?[01])*$`), "0b", 2},
+}
+
+// parseTOMLScalar parses a bare boolean or integer.
+func parseTOMLScalar(text string, line int) (any, error) {
+	switch text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	for _, form := range tomlIntegers {
+		if !form.pattern.MatchString(text) {
+			continue
+		}
+		digits := strings.ReplaceAll(strings.TrimPrefix(text, form.prefix), "_", "")
+		n, err := strconv.ParseInt(digits, form.base, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: integer %s is out of range", line, text)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("line %d: invalid value %q (strings must be quoted)", line, text)
+}
+
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++ // '['
+	values := []any{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.consume(']') {
+			return values, nil
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipBlank()
+		if p.consume(',') {
+			continue
+		}
+		if p.consume(']') {
+			return values, nil
+		}
+		return nil, p.errorf("expected ',' or ']' in array")
+	}
+}
+
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++ // opening quote
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated string")
+			}
+			escape := p.peek()
+			p.pos++
+			switch escape {
+			case '"', '\\':
+				b.WriteByte(escape)
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'u', 'U':
+				size := 4
+				if escape == 'U' {
+					size = 8
+				}
+				if p.pos+size > len(p.src) {
+					return "", p.errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(code)) {
+					return "", p.errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				p.pos += size
+			default:
+				return "", p.errorf("invalid escape sequence \\%c", escape)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *tomlParser) literalString() (string, error) {
+	p.pos++ // opening quote
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	value := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return value, nil
+}
+
+// endOfLine accepts trailing spaces and a comment after a statement.
+func (p *tomlParser) endOfLine() error {
+	p.skipSpaces()
+	if p.eof() {
+		return nil
+	}
+	switch p.peek() {
+	case '#':
+		p.skipComment()
+		return nil
+	case '\r', '\n':
+		return nil
+	}
+	return p.errorf("unexpected %q after value", p.peek())
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) consume(c byte) bool {
+	if !p.eof() && p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *tomlParser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
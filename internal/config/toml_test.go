@@ -0,0 +1,187 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want map[string]any
+	}{
+		{
+			name: "empty",
+			doc:  "# nothing here\n\n",
+			want: map[string]any{},
+		},
+		{
+			name: "scalars",
+			doc: `output = "context.md"   # trailing comment
+template = 'C:\templates\ctx.tmpl'
+tree = true
+gitignore = false
+max_tokens = 100_000
+tree_depth = -1
+zero = 0
+signed = +7
+hex = 0xff
+octal = 0o17
+binary = 0b101
+`,
+			want: map[string]any{
+				"output":     "context.md",
+				"template":   `C:\templates\ctx.tmpl`,
+				"tree":       true,
+				"gitignore":  false,
+				"max_tokens": int64(100000),
+				"tree_depth": int64(-1),
+				"zero":       int64(0),
+				"signed":     int64(7),
+				"hex":        int64(255),
+				"octal":      int64(15),
+				"binary":     int64(5),
+			},
+		},
+		{
+			name: "string escapes",
+			doc:  `text = "tab\there \"quoted\" \\ \u00e9\U0001F600"` + "\n",
+			want: map[string]any{"text": "tab\there \"quoted\" \\ é😀"},
+		},
+		{
+			name: "arrays",
+			doc: `exclude = [
+  "*.min.js",   # minified
+  '**/mocks/**',
+]
+empty = []
+numbers = [1, 2, 3]
+`,
+			want: map[string]any{
+				"exclude": []any{"*.min.js", "**/mocks/**"},
+				"empty":   []any{},
+				"numbers": []any{int64(1), int64(2), int64(3)},
+			},
+		},
+		{
+			name: "tables",
+			doc: `[theme]
+selected = "34"
+
+[profiles.api]
+paths = ["internal/api"]
+
+[profiles."tui only"]
+format = "markdown"
+`,
+			want: map[string]any{
+				"theme": map[string]any{"selected": "34"},
+				"profiles": map[string]any{
+					"api":      map[string]any{"paths": []any{"internal/api"}},
+					"tui only": map[string]any{"format": "markdown"},
+				},
+			},
+		},
+		{
+			name: "dotted keys",
+			doc: `theme.selected = "34"
+theme . hint = "86"
+"quoted key".value = 1
+
+[profiles]
+api.format = "xml"
+api.paths = ["api"]
+`,
+			want: map[string]any{
+				"theme":      map[string]any{"selected": "34", "hint": "86"},
+				"quoted key": map[string]any{"value": int64(1)},
+				"profiles": map[string]any{
+					"api": map[string]any{"format": "xml", "paths": []any{"api"}},
+				},
+			},
+		},
+		{
+			name: "crlf line endings",
+			doc:  "[theme]\r\nselected = \"34\"\r\n",
+			want: map[string]any{"theme": map[string]any{"selected": "34"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("parseTOML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTOML =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		// want is a part of the error message.
+		want string
+	}{
+		{name: "leading zero", doc: "max_tokens = 010\n", want: `line 1: invalid value "010"`},
+		{name: "signed hex", doc: "n = -0x10\n", want: `invalid value "-0x10"`},
+		{name: "upper-case prefix", doc: "n = 0X10\n", want: `invalid value "0X10"`},
+		{name: "leading underscore", doc: "n = _1\n", want: `invalid value "_1"`},
+		{name: "trailing underscore", doc: "n = 1_\n", want: `invalid value "1_"`},
+		{name: "double underscore", doc: "n = 1__0\n", want: `invalid value "1__0"`},
+		{name: "out of range", doc: "n = 9223372036854775808\n", want: "out of range"},
+		{name: "float", doc: "n = 1.5\n", want: `invalid value "1.5"`},
+		{name: "unquoted string", doc: "format = markdown\n", want: "strings must be quoted"},
+		{name: "missing equals", doc: "format \"markdown\"\n", want: `expected '=' after key "format"`},
+		{name: "missing value", doc: "format =", want: "expected a value"},
+		{name: "unterminated string", doc: "format = \"markdown\n", want: "unterminated string"},
+		{name: "unterminated literal string", doc: "format = 'markdown\n", want: "unterminated string"},
+		{name: "multi-line string", doc: `format = """x"""` + "\n", want: "multi-line strings are not supported"},
+		{name: "invalid escape", doc: `format = "\q"` + "\n", want: `invalid escape sequence \q`},
+		{name: "invalid unicode escape", doc: `format = "\uZZZZ"` + "\n", want: "invalid unicode escape"},
+		{name: "unterminated array", doc: "exclude = [\"a\",\n", want: "unterminated array"},
+		{name: "missing comma", doc: "exclude = [\"a\" \"b\"]\n", want: "expected ',' or ']' in array"},
+		{name: "text after value", doc: "tree = true false\n", want: `unexpected 'f' after value`},
+		{name: "duplicate key", doc: "tree = true\ntree = false\n", want: `line 2: key "tree" defined more than once`},
+		{name: "duplicate dotted key", doc: "theme.hint = \"1\"\ntheme.hint = \"2\"\n", want: `key "theme.hint" defined more than once`},
+		{name: "duplicate table", doc: "[theme]\n[theme]\n", want: `table "theme" defined more than once`},
+		{name: "table after dotted key", doc: "theme.hint = \"1\"\n[theme]\n", want: `table "theme" defined more than once`},
+		{name: "dotted key through a value", doc: "theme = 1\ntheme.hint = \"2\"\n", want: `key "theme" is not a table`},
+		{name: "table through a value", doc: "theme = 1\n[theme.colors]\n", want: `key "theme" is not a table`},
+		{name: "unterminated table", doc: "[theme\n", want: "expected ']' after table name"},
+		{name: "empty key", doc: "= 1\n", want: "unexpected character '=' in key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML([]byte(tt.doc))
+			if err == nil {
+				t.Fatalf("parseTOML(%q) succeeded, want an error containing %q", tt.doc, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseTOML(%q) = %v, want an error containing %q", tt.doc, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseFileDottedKeys checks that dotted keys reach the settings they
+// name, the same as a table header would.
+func TestParseFileDottedKeys(t *testing.T) {
+	file, err := ParseFile([]byte("theme.selected = \"34\"\nprofiles.api.paths = [\"api\"]\n"), "getctx.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Theme == nil || file.Theme.Selected != "34" {
+		t.Errorf("theme = %+v, want selected 34", file.Theme)
+	}
+	if paths := file.Profiles["api"].Paths; !reflect.DeepEqual(paths, []string{"api"}) {
+		t.Errorf("profile paths = %v, want [api]", paths)
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
+)
+
+// CheckFile reports every problem in a configuration file instead of
+// stopping at the first one: syntax errors, unknown keys, values of the wrong
+// type and invalid exclusion patterns.
+func CheckFile(data []byte, path string) []error {
+	var values map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return []error{fmt.Errorf("%s: %w", path, err)}
+		}
+	} else {
+		var err error
+		if values, err = parseTOML(data); err != nil {
+			return []error{fmt.Errorf("%s: %w", path, err)}
+		}
+	}
+
+	var errs []error
+	for _, key := range unknownKeys(values, reflect.TypeOf(File{}), "") {
+		errs = append(errs, fmt.Errorf("%s: unknown key %q", path, key))
+	}
+
+	errs = append(errs, checkPatterns(values["exclude"], path, "exclusion")...)
+	if profiles, ok := values["profiles"].(map[string]any); ok {
+		for _, name := range slices.Sorted(maps.Keys(profiles)) {
+			profile, ok := profiles[name].(map[string]any)
+			if !ok {
+				continue
+			}
+			source := fmt.Sprintf("%s: profile %q", path, name)
+			errs = append(errs, checkPatterns(profile["exclude"], source, "exclusion")...)
+			errs = append(errs, checkPatterns(profile["include"], source, "include")...)
+		}
+	}
+
+	// Decode what is left to find values of the wrong type.
+	cleaned, err := json.Marshal(values)
+	if err == nil {
+		_, err = decodeFile(cleaned)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+	return errs
+}
+
+// checkPatterns reports the invalid entries of a list of gitignore-style
+// patterns. Values that are not lists of strings are left to the decoder.
+func checkPatterns(value any, source, kind string) []error {
+	patterns, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+
+	var errs []error
+	for _, pattern := range patterns {
+		line, ok := pattern.(string)
+		if !ok {
+			continue
+		}
+		if parsed, err := ignore.ParsePattern(line, source, 0); err != nil {
+			errs = append(errs, err)
+		} else if parsed == nil {
+			errs = append(errs, fmt.Errorf("%s: empty %s pattern %q", source, kind, line))
+		}
+	}
+	return errs
+}
+
+// unknownKeys removes the keys that do not correspond to a field of typ from
+// values, descending into nested tables, and returns their dotted names.
+func unknownKeys(values map[string]any, typ reflect.Type, prefix string) []string {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		fields[name] = field.Type
+	}
+
+	var unknown []string
+	for key, value := range values {
+		fieldType, ok := fields[key]
+		if !ok {
+			unknown = append(unknown, prefix+key)
+			delete(values, key)
+			continue
+		}
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		nested, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch {
+		case fieldType.Kind() == reflect.Struct:
+			unknown = append(unknown, unknownKeys(nested, fieldType, prefix+key+".")...)
+		case fieldType.Kind() == reflect.Map && fieldType.Elem().Kind() == reflect.Struct:
+			for name, entry := range nested {
+				if table, ok := entry.(map[string]any); ok {
+					unknown = append(unknown, unknownKeys(table, fieldType.Elem(), prefix+key+"."+name+".")...)
+				}
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
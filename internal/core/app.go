@@ -8,6 +8,7 @@ import (
 	"github.com/kacperzielinskidev/getctx/internal/config"
 	"github.com/kacperzielinskidev/getctx/internal/fs"
 	"github.com/kacperzielinskidev/getctx/internal/logger"
+	"github.com/kacperzielinskidev/getctx/internal/session"
 	"github.com/kacperzielinskidev/getctx/internal/tui"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -22,6 +23,10 @@ type App struct {
 	fsys           fs.FileSystem
 	startPath      string
 	outputFilename string
+	// initialSelection pre-populates the TUI, e.g. from an imported list.
+	initialSelection []string
+	// selection holds the paths of the last build, for exporting them.
+	selection []string
 }
 
 func NewApp(
@@ -31,25 +36,37 @@ func NewApp(
 	fsys fs.FileSystem,
 	startPath string,
 	outputFilename string,
+	initialSelection []string,
 
 ) *App {
 	return &App{
-		log:            log,
-		contextBuilder: builder,
-		config:         cfg,
-		fsys:           fsys,
-		startPath:      startPath,
-		outputFilename: outputFilename,
+		log:              log,
+		contextBuilder:   builder,
+		config:           cfg,
+		fsys:             fsys,
+		startPath:        startPath,
+		outputFilename:   outputFilename,
+		initialSelection: initialSelection,
 	}
 }
 
+// Selection returns the paths the last build was started with.
+func (a *App) Selection() []string {
+	return a.selection
+}
+
 func (a *App) Run() (*build.BuildResult, error) {
-	model, err := tui.NewModel(a.startPath, a.config, a.fsys)
+	model, err := tui.NewModel(a.startPath, a.config, a.fsys, a.contextBuilder)
 	if err != nil {
 		err = fmt.Errorf("error initializing TUI model: %w", err)
 		a.log.Error("App.Run.NewModel", err)
 		return nil, err
 	}
+	if len(a.initialSelection) > 0 {
+		model.Select(a.initialSelection)
+	}
+
+	store := a.offerSession(model)
 
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
@@ -67,18 +84,74 @@ func (a *App) Run() (*build.BuildResult, error) {
 		return nil, err
 	}
 
+	if store != nil {
+		if s := m.Session(); s != nil {
+			if err := store.Save(s); err != nil {
+				a.log.Warn("App.Run.SaveSession", err)
+			}
+		}
+	}
+
 	if m.Aborted {
 		a.log.Info("App.Run", "User aborted the operation.")
 		return nil, ErrAbortedByUser
 	}
 
-	result, err := a.contextBuilder.Build(m.GetSelectedPaths(), a.outputFilename)
+	// A profile picked in the TUI may have changed the output file.
+	a.selection = m.GetSelectedPaths()
+	result, err := a.contextBuilder.Build(a.selection, a.config.OutputFilename)
 
 	if err != nil {
 		err = fmt.Errorf("a critical error occurred while creating the context file: %w", err)
 		a.log.Error("App.Run.BuildContext", err)
-		return nil, err
+		return result, err
 	}
 	return result, nil
 
 }
+
+// offerSession hands the saved session of the project to the model and
+// returns the store to save the new one in, or nil when sessions are off or
+// unavailable. A profile or an imported list given on the command line
+// replaces the selection, so no session is offered then. Session problems are
+// logged, never fatal.
+func (a *App) offerSession(model *tui.Model) *session.Store {
+	if !a.config.Session {
+		return nil
+	}
+	store, err := session.NewStore(a.fsys)
+	if err != nil {
+		a.log.Warn("App.offerSession.NewStore", err)
+		return nil
+	}
+	if a.config.Profile != "" || len(a.initialSelection) > 0 {
+		return store
+	}
+
+	saved, err := store.Load(a.config.Exclude.Base)
+	if err != nil {
+		a.log.Warn("App.offerSession.Load", err)
+		return store
+	}
+	if saved != nil {
+		model.OfferSession(saved)
+	}
+	return store
+}
+
+// RunHeadless builds the context from the given paths without starting the
+// TUI. A failed build returns its partial result along with the error.
+func (a *App) RunHeadless(paths []string) (*build.BuildResult, error) {
+	a.log.Info("App.RunHeadless", map[string]any{
+		"path_count": len(paths),
+	})
+
+	a.selection = paths
+	result, err := a.contextBuilder.Build(paths, a.outputFilename)
+	if err != nil {
+		err = fmt.Errorf("a critical error occurred while creating the context file: %w", err)
+		a.log.Error("App.RunHeadless.BuildContext", err)
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,202 @@
+package filter
+
+import (
+	iofs "io/fs"
+	"os"
+
+	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
+)
+
+// Reason explains a Decision.
+type Reason int
+
+const (
+	// Included means no rule left the path out. Decision.Rule may still hold
+	// the negated pattern that brought it back.
+	Included Reason = iota
+	// ExcludedByRule means an exclusion pattern matched: a built-in default,
+	// --exclude or a .getctxignore file.
+	ExcludedByRule
+	// NotIncluded means the file lies below a .getctxinclude file, or inside
+	// the project while a profile with include patterns is in use, but
+	// matches none of those patterns.
+	NotIncluded
+	// Gitignored means git ignores the path.
+	Gitignored
+	// OutputFile means the path is the configured output file or another
+	// file the run writes, which must never be fed back into the next
+	// context.
+	OutputFile
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Included:
+		return "included"
+	case ExcludedByRule:
+		return "excluded by rule"
+	case NotIncluded:
+		return "not in include list"
+	case Gitignored:
+		return "ignored by git"
+	case OutputFile:
+		return "an output file"
+	default:
+		return "unknown"
+	}
+}
+
+// Decision is the verdict for one path, with the pattern that decided it.
+type Decision struct {
+	Reason Reason
+	Rule   *ignore.Pattern
+}
+
+func (d Decision) Excluded() bool {
+	return d.Reason != Included
+}
+
+// Filter is the single place that decides which paths are left out. The TUI,
+// file discovery and the tree map all go through it, so they always agree.
+type Filter struct {
+	fsys      ignore.FileSystem
+	config    *config.Config
+	gitignore *ignore.Matcher
+	outputs   []outputFile
+}
+
+// outputFile identifies a file the run writes by absolute path and, when it
+// already exists, by inode, which also catches links to it.
+type outputFile struct {
+	path string
+	info iofs.FileInfo
+}
+
+func New(fsys ignore.FileSystem, cfg *config.Config) *Filter {
+	f := &Filter{fsys: fsys, config: cfg}
+	if cfg.RespectGitignore {
+		f.gitignore = ignore.NewMatcher(fsys)
+	}
+	for _, path := range append([]string{cfg.OutputFilename}, cfg.GeneratedFiles...) {
+		if path == "" {
+			continue
+		}
+		if output, err := fsys.Abs(path); err == nil {
+			info, _ := fsys.Stat(output)
+			f.outputs = append(f.outputs, outputFile{path: output, info: info})
+		}
+	}
+	return f
+}
+
+// Excludes reports whether path is left out.
+func (f *Filter) Excludes(path string, entry iofs.DirEntry) bool {
+	return f.Decide(path, entry).Excluded()
+}
+
+// Decide evaluates the rules for path in order of precedence. The output
+// file and the other files the run writes are always left out, whatever the
+// patterns say. The exclusion
+// patterns come first and .getctxignore rules override them in either
+// direction (a "!vendor/" line brings vendor back). Files below a directory
+// with a .getctxinclude must then match one of its patterns; directories are
+// never excluded by include rules so they can be traversed. The include
+// patterns of the active profile work the same way. Finally git's ignore
+// rules apply on their own.
+func (f *Filter) Decide(path string, entry iofs.DirEntry) Decision {
+	absPath, err := f.fsys.Abs(path)
+	if err != nil {
+		return Decision{Reason: Included}
+	}
+	isDir := entry.IsDir()
+	if !isDir && f.isOutput(absPath, entry) {
+		return Decision{Reason: OutputFile}
+	}
+
+	decision := Decision{Reason: Included}
+	decide := func(pattern *ignore.Pattern) {
+		if pattern == nil {
+			return
+		}
+		decision.Rule = pattern
+		if pattern.Negate {
+			decision.Reason = Included
+		} else {
+			decision.Reason = ExcludedByRule
+		}
+	}
+
+	decide(f.config.Exclude.Match(absPath, isDir))
+	decide(lastMatch(f.config.IgnoreRules, absPath, isDir))
+	if decision.Excluded() {
+		return decision
+	}
+
+	if !isDir && covered(f.config.IncludeRules, absPath) {
+		pattern := lastMatch(f.config.IncludeRules, absPath, isDir)
+		if pattern == nil || pattern.Negate {
+			return Decision{Reason: NotIncluded, Rule: pattern}
+		}
+	}
+	if profile := f.config.ProfileInclude; !isDir && profile != nil && profile.Covers(absPath) {
+		pattern := profile.Match(absPath, isDir)
+		if pattern == nil || pattern.Negate {
+			return Decision{Reason: NotIncluded, Rule: pattern}
+		}
+	}
+
+	if pattern := f.gitignore.Explain(absPath, isDir); pattern != nil {
+		if !pattern.Negate {
+			return Decision{Reason: Gitignored, Rule: pattern}
+		}
+		decision.Rule = pattern
+	}
+
+	return decision
+}
+
+// isOutput reports whether the file is one the run writes, a temporary file
+// left behind while writing one, or a hard link to one that already exists.
+// Only regular files of the same size and mode are compared by inode.
+func (f *Filter) isOutput(absPath string, entry iofs.DirEntry) bool {
+	var info iofs.FileInfo
+	for _, output := range f.outputs {
+		if absPath == output.path || fs.IsAtomicTemp(absPath, output.path) {
+			return true
+		}
+		if output.info == nil || !entry.Type().IsRegular() {
+			continue
+		}
+		if info == nil {
+			var err error
+			if info, err = entry.Info(); err != nil {
+				return false
+			}
+		}
+		if info.Size() == output.info.Size() && info.Mode() == output.info.Mode() && os.SameFile(info, output.info) {
+			return true
+		}
+	}
+	return false
+}
+
+func lastMatch(ruleSets []*ignore.Rules, absPath string, isDir bool) *ignore.Pattern {
+	var last *ignore.Pattern
+	for _, rules := range ruleSets {
+		if pattern := rules.Match(absPath, isDir); pattern != nil {
+			last = pattern
+		}
+	}
+	return last
+}
+
+func covered(ruleSets []*ignore.Rules, absPath string) bool {
+	for _, rules := range ruleSets {
+		if rules.Covers(absPath) {
+			return true
+		}
+	}
+	return false
+}
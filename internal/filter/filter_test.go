@@ -0,0 +1,110 @@
+package filter_test
+
+import (
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
+)
+
+func TestDecide(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		".git/HEAD":           "ref: refs/heads/main\n",
+		".gitignore":          "*.log\n",
+		".getctxignore":       "!vendor/\nsecret.txt\n",
+		"main.go":             "package main\n",
+		"vendor/dep/dep.go":   "package dep\n",
+		"secret.txt":          "secret\n",
+		"debug.log":           "debug\n",
+		"context.md":          "previous context\n",
+		"report.json":         "{}\n",
+		".context.md.123.tmp": "half a context\n",
+		".other.md.123.tmp":   "someone else's\n",
+		"assets/logo.PNG":     "not really a png\n",
+		"docs/.getctxinclude": "*.md\n",
+		"docs/guide.md":       "# guide\n",
+		"docs/notes.txt":      "notes\n",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Link(filepath.Join(root, "context.md"), filepath.Join(root, "context-link.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fs.NewOSFileSystem()
+	cfg := config.NewConfig()
+	cfg.Exclude.Base = root
+	cfg.OutputFilename = filepath.Join(root, "context.md")
+	cfg.GeneratedFiles = []string{filepath.Join(root, "report.json")}
+	var err error
+	if cfg.IgnoreRules, err = ignore.LoadProjectRules(fsys, filepath.Join(root, "docs"), ignore.ProjectIgnoreFile); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IncludeRules, err = ignore.LoadProjectRules(fsys, filepath.Join(root, "docs"), ignore.ProjectIncludeFile); err != nil {
+		t.Fatal(err)
+	}
+	pathFilter := filter.New(fsys, cfg)
+
+	tests := []struct {
+		path   string
+		reason filter.Reason
+		// rule is the text of the deciding pattern, empty for none.
+		rule string
+	}{
+		{path: "main.go", reason: filter.Included},
+		{path: ".git", reason: filter.ExcludedByRule, rule: ".git"},
+		{path: "assets/logo.PNG", reason: filter.ExcludedByRule, rule: "*.png"},
+		{path: "vendor", reason: filter.Included, rule: "!vendor/"},
+		{path: "vendor/dep/dep.go", reason: filter.Included},
+		{path: "secret.txt", reason: filter.ExcludedByRule, rule: "secret.txt"},
+		{path: "debug.log", reason: filter.Gitignored, rule: "*.log"},
+		{path: "context.md", reason: filter.OutputFile},
+		{path: "report.json", reason: filter.OutputFile},
+		{path: ".context.md.123.tmp", reason: filter.OutputFile},
+		{path: ".other.md.123.tmp", reason: filter.Included},
+		{path: "context-link.md", reason: filter.OutputFile},
+		{path: "docs", reason: filter.Included},
+		{path: "docs/guide.md", reason: filter.Included},
+		{path: "docs/notes.txt", reason: filter.NotIncluded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join(root, tt.path)
+			info, err := os.Lstat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decision := pathFilter.Decide(path, iofs.FileInfoToDirEntry(info))
+			if decision.Reason != tt.reason {
+				t.Errorf("reason = %v, want %v", decision.Reason, tt.reason)
+			}
+			rule := ""
+			if decision.Rule != nil {
+				rule = decision.Rule.Text
+			}
+			if rule != tt.rule {
+				t.Errorf("rule = %q, want %q", rule, tt.rule)
+			}
+			if excluded := pathFilter.Excludes(path, iofs.FileInfoToDirEntry(info)); excluded != decision.Excluded() {
+				t.Errorf("Excludes = %v, Decide says %v", excluded, decision.Excluded())
+			}
+		})
+	}
+}
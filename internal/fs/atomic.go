@@ -0,0 +1,91 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// AtomicFile collects writes in a temporary file next to its target and only
+// replaces the target on Commit, so readers never see a partial file and a
+// failed write leaves the previous one in place.
+type AtomicFile struct {
+	fsys     FileSystem
+	file     io.WriteCloser
+	path     string
+	tempPath string
+	done     bool
+}
+
+// CreateAtomic starts writing path. The temporary file lives in the same
+// directory, so the final rename never crosses file systems.
+func CreateAtomic(fsys FileSystem, path string) (*AtomicFile, error) {
+	file, tempPath, err := fsys.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"+tempSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{fsys: fsys, file: file, path: path, tempPath: tempPath}, nil
+}
+
+const tempSuffix = ".tmp"
+
+// IsAtomicTemp reports whether path is a temporary file CreateAtomic made for
+// target, such as one left behind by a run that was killed. Both paths must
+// be absolute.
+func IsAtomicTemp(path, target string) bool {
+	if filepath.Dir(path) != filepath.Dir(target) {
+		return false
+	}
+	name, prefix := filepath.Base(path), "."+filepath.Base(target)+"."
+	return len(name) > len(prefix)+len(tempSuffix) && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, tempSuffix)
+}
+
+func (f *AtomicFile) Write(p []byte) (int, error) {
+	return f.file.Write(p)
+}
+
+// Commit flushes the temporary file to disk, closes it and moves it over the
+// target, so a crash leaves either the old or the new content, never an
+// empty file. The target keeps its permissions; a new one gets the usual
+// 0644.
+func (f *AtomicFile) Commit() error {
+	f.done = true
+	if syncer, ok := f.file.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			f.file.Close()
+			f.fsys.Remove(f.tempPath)
+			return err
+		}
+	}
+	if err := f.file.Close(); err != nil {
+		f.fsys.Remove(f.tempPath)
+		return err
+	}
+
+	mode := fs.FileMode(0o644)
+	if info, err := f.fsys.Stat(f.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := f.fsys.Chmod(f.tempPath, mode); err != nil {
+		f.fsys.Remove(f.tempPath)
+		return err
+	}
+	if err := f.fsys.Rename(f.tempPath, f.path); err != nil {
+		f.fsys.Remove(f.tempPath)
+		return fmt.Errorf("could not replace %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// Abort discards the temporary file. It does nothing after Commit, so it can
+// be deferred right after CreateAtomic.
+func (f *AtomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.file.Close()
+	f.fsys.Remove(f.tempPath)
+}
@@ -11,7 +11,14 @@ type FileSystem interface {
 	Abs(path string) (string, error)
 	ReadFile(name string) ([]byte, error)
 	Create(name string) (io.WriteCloser, error)
+	// CreateTemp creates a new file in dir, like os.CreateTemp, and returns
+	// it together with its name.
+	CreateTemp(dir, pattern string) (io.WriteCloser, string, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	Chmod(name string, mode fs.FileMode) error
 	WalkDir(root string, fn fs.WalkDirFunc) error
 	UserHomeDir() (string, error)
 	Open(name string) (fs.File, error)
+	MkdirAll(path string, perm fs.FileMode) error
 }
@@ -33,6 +33,26 @@ func (fsys *OSFileSystem) Create(name string) (io.WriteCloser, error) {
 	return os.Create(name)
 }
 
+func (fsys *OSFileSystem) CreateTemp(dir, pattern string) (io.WriteCloser, string, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, file.Name(), nil
+}
+
+func (fsys *OSFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (fsys *OSFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (fsys *OSFileSystem) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
 func (fsys *OSFileSystem) WalkDir(root string, fn fs.WalkDirFunc) error {
 	return filepath.WalkDir(root, fn)
 }
@@ -44,3 +64,7 @@ func (fsys *OSFileSystem) UserHomeDir() (string, error) {
 func (fsys *OSFileSystem) Open(name string) (fs.File, error) {
 	return os.Open(name)
 }
+
+func (fsys *OSFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
@@ -0,0 +1,32 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ReadPathList parses a list of paths as produced by tools like `git ls-files`
+// or `fd`. Entries are separated by NUL bytes if the input contains any
+// (`-z`/`-0` modes), otherwise by newlines. Empty entries are ignored.
+func ReadPathList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read path list: %w", err)
+	}
+
+	separator := []byte{'\n'}
+	if bytes.IndexByte(data, 0) >= 0 {
+		separator = []byte{0}
+	}
+
+	var paths []string
+	for _, entry := range bytes.Split(data, separator) {
+		entry = bytes.TrimSuffix(entry, []byte{'\r'})
+		if len(bytes.TrimSpace(entry)) == 0 {
+			continue
+		}
+		paths = append(paths, string(entry))
+	}
+	return paths, nil
+}
@@ -1,7 +1,9 @@
 package fs
 
 import (
-	"fmt"
+	"bufio"
+	"context"
+	"errors"
 	"io"
 	"io/fs"
 	"net/http"
@@ -9,61 +11,70 @@ import (
 	"strings"
 )
 
-func DiscoverFiles(fsys FileSystem, paths []string, excludedNames map[string]struct{}) ([]string, []string, error) {
-	var discoveredPaths []string
-	var warnings []string
+// PathFilter decides which paths are left out of discovery.
+type PathFilter interface {
+	Excludes(path string, entry fs.DirEntry) bool
+}
 
-	for _, path := range paths {
-		if _, ok := excludedNames[filepath.Base(path)]; ok {
-			continue
-		}
+// DiscoverFiles expands the given paths into the files that pass filter. A
+// path or directory entry that can not be read is returned as a
+// *fs.PathError and does not stop the rest of the walk.
+func DiscoverFiles(fsys FileSystem, paths []string, filter PathFilter) ([]string, []error, error) {
+	return DiscoverFilesParallel(context.Background(), fsys, paths, filter, 1)
+}
 
-		info, err := fsys.Stat(path)
-		if err != nil {
-			warnings = append(warnings, fmt.Sprintf("Could not stat path %s: %v", path, err))
-			continue
-		}
+func asPathError(op, path string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr
+	}
+	return &fs.PathError{Op: op, Path: path, Err: err}
+}
 
-		if info.IsDir() {
-			err := fsys.WalkDir(path, func(subPath string, d fs.DirEntry, err error) error {
-				if err != nil {
-					return err
-				}
+// RelativeTo returns path relative to root with forward slashes, or path
+// itself if it lies outside of root.
+func RelativeTo(root, path string) string {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(relPath)
+}
 
-				if _, ok := excludedNames[d.Name()]; ok {
-					if d.IsDir() {
-						return filepath.SkipDir
-					}
-					return nil
-				}
+// SniffLen is how many leading bytes content type detection looks at.
+const SniffLen = 512
 
-				if !d.IsDir() {
-					discoveredPaths = append(discoveredPaths, subPath)
-				}
-				return nil
-			})
-			if err != nil {
-				warnings = append(warnings, fmt.Sprintf("Error walking directory %s: %v", path, err))
-			}
-		} else {
-			discoveredPaths = append(discoveredPaths, path)
-		}
+func IsTextFile(fsys FileSystem, path string) (bool, error) {
+	contentType, err := DetectContentType(fsys, path)
+	if err != nil {
+		return false, err
 	}
+	return IsText(contentType), nil
+}
 
-	return discoveredPaths, warnings, nil
+// IsText reports whether a detected content type counts as text.
+func IsText(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/")
 }
 
-func IsTextFile(fsys FileSystem, path string) (bool, error) {
+// DetectContentType sniffs the MIME type of a file from its first 512 bytes.
+func DetectContentType(fsys FileSystem, path string) (string, error) {
 	file, err := fsys.Open(path)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	defer file.Close()
-	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
+	contentType, _, err := SniffContentType(bufio.NewReaderSize(file, SniffLen))
+	return contentType, err
+}
+
+// SniffContentType detects the MIME type from the start of r without
+// consuming anything, so the same reader can be copied afterwards. It also
+// returns the sniffed bytes, which are only valid until r is read.
+func SniffContentType(r *bufio.Reader) (string, []byte, error) {
+	head, err := r.Peek(SniffLen)
 	if err != nil && err != io.EOF {
-		return false, err
+		return "", nil, err
 	}
-	contentType := http.DetectContentType(buffer[:n])
-	return strings.HasPrefix(contentType, "text/"), nil
+	return http.DetectContentType(head), head, nil
 }
@@ -0,0 +1,123 @@
+package fs
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DiscoverFilesParallel is DiscoverFiles with up to jobs directories read at
+// once. The files and errors come back in the order a sequential walk finds
+// them, whatever the number of jobs. Once ctx is done, no further directory
+// is read and ctx's error is returned.
+func DiscoverFilesParallel(ctx context.Context, fsys FileSystem, paths []string, filter PathFilter, jobs int) ([]string, []error, error) {
+	w := &walker{ctx: ctx, fsys: fsys, filter: filter}
+	// The calling goroutine walks too, so it takes one of the jobs.
+	w.group.SetLimit(max(jobs, 1) - 1)
+
+	roots := make([]*walkNode, 0, len(paths))
+	for _, path := range paths {
+		info, err := fsys.Stat(path)
+		if err != nil {
+			roots = append(roots, &walkNode{err: asPathError("stat", path, err)})
+			continue
+		}
+
+		if filter.Excludes(path, fs.FileInfoToDirEntry(info)) {
+			continue
+		}
+
+		if !info.IsDir() {
+			roots = append(roots, &walkNode{items: []walkItem{{path: path}}})
+			continue
+		}
+		root := &walkNode{path: path}
+		roots = append(roots, root)
+		w.spawn(root)
+	}
+	w.group.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var discoveredPaths []string
+	var failures []error
+	for _, root := range roots {
+		root.flatten(&discoveredPaths, &failures)
+	}
+	return discoveredPaths, failures, nil
+}
+
+type walker struct {
+	ctx    context.Context
+	fsys   FileSystem
+	filter PathFilter
+	group  errgroup.Group
+}
+
+// walkNode holds what a walk found in one directory: its files and
+// subdirectories in the order ReadDir returned them, or why it could not be
+// read. Each node is only written by the goroutine that reads its directory.
+type walkNode struct {
+	path  string
+	items []walkItem
+	err   error
+}
+
+// walkItem is a discovered file or, with dir set, a subdirectory.
+type walkItem struct {
+	path string
+	dir  *walkNode
+}
+
+// spawn reads node's directory on another goroutine if a job is free, and on
+// the current one otherwise, so a full pool can never block the walk.
+func (w *walker) spawn(node *walkNode) {
+	if !w.group.TryGo(func() error {
+		w.walk(node)
+		return nil
+	}) {
+		w.walk(node)
+	}
+}
+
+func (w *walker) walk(node *walkNode) {
+	if w.ctx.Err() != nil {
+		return
+	}
+	entries, err := w.fsys.ReadDir(node.path)
+	if err != nil {
+		node.err = asPathError("walk", node.path, err)
+		return
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(node.path, entry.Name())
+		if w.filter.Excludes(path, entry) {
+			continue
+		}
+		if !entry.IsDir() {
+			node.items = append(node.items, walkItem{path: path})
+			continue
+		}
+		child := &walkNode{path: path}
+		node.items = append(node.items, walkItem{dir: child})
+		w.spawn(child)
+	}
+}
+
+// flatten appends the files and errors below n depth first.
+func (n *walkNode) flatten(files *[]string, failures *[]error) {
+	if n.err != nil {
+		*failures = append(*failures, n.err)
+	}
+	for _, item := range n.items {
+		if item.dir != nil {
+			item.dir.flatten(files, failures)
+		} else {
+			*files = append(*files, item.path)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	gitignoreFile = ".gitignore"
+	gitDir        = ".git"
+)
+
+// gitDirPattern explains why the repository's own .git directory is ignored
+// even without any rule for it.
+var gitDirPattern = &Pattern{Text: gitDir + "/", Source: "git", DirOnly: true}
+
+// FileSystem is the part of fs.FileSystem the matcher needs.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	Stat(name string) (iofs.FileInfo, error)
+	Abs(path string) (string, error)
+	UserHomeDir() (string, error)
+}
+
+// Matcher answers whether git would ignore a path. It follows git's rules:
+// inside a repository, the global excludes file, .git/info/exclude and every
+// .gitignore from the repository root down to the path are consulted in
+// increasing order of precedence, and nothing below an ignored directory can
+// be re-included. The repository's .git directory is always ignored. Paths
+// outside a repository are never ignored.
+//
+// A nil *Matcher ignores nothing. Matcher is safe for concurrent use.
+type Matcher struct {
+	fsys FileSystem
+
+	// The caches are keyed by absolute directory. Matching itself holds no
+	// lock, so parallel walkers only wait for the same file to load.
+	repoRoots   memo[string]
+	dirRules    memo[*Rules]
+	repoRules   memo[[]*Rules]
+	dirDecision memo[*Pattern]
+	globalOnce  sync.Once
+	global      *Rules
+}
+
+func NewMatcher(fsys FileSystem) *Matcher {
+	return &Matcher{fsys: fsys}
+}
+
+// Match reports whether path is ignored.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	pattern := m.Explain(path, isDir)
+	return pattern != nil && !pattern.Negate
+}
+
+// Explain returns the last pattern that matches path, or nil. A negated
+// pattern means the path was ignored and then re-included. The pattern may
+// belong to a parent directory of path, which can not be re-included.
+func (m *Matcher) Explain(path string, isDir bool) *Pattern {
+	if m == nil {
+		return nil
+	}
+
+	absPath, err := m.fsys.Abs(path)
+	if err != nil {
+		return nil
+	}
+
+	repoRoot := m.repoRoot(filepath.Dir(absPath))
+	if repoRoot == "" || absPath == repoRoot {
+		return nil
+	}
+
+	relPath, err := filepath.Rel(repoRoot, absPath)
+	if err != nil {
+		return nil
+	}
+	components := strings.Split(relPath, string(filepath.Separator))
+	if components[0] == gitDir {
+		return gitDirPattern
+	}
+
+	dir := repoRoot
+	for _, component := range components[:len(components)-1] {
+		dir = filepath.Join(dir, component)
+		if pattern := m.dirIgnored(repoRoot, dir); pattern != nil && !pattern.Negate {
+			return pattern
+		}
+	}
+
+	return m.decide(repoRoot, absPath, isDir)
+}
+
+func (m *Matcher) dirIgnored(repoRoot, dir string) *Pattern {
+	return m.dirDecision.get(dir, func() *Pattern {
+		return m.decide(repoRoot, dir, true)
+	})
+}
+
+// decide evaluates every rule set that applies to absPath, from the lowest to
+// the highest precedence; the last match wins and a negation un-ignores.
+// The winning pattern is returned even when it is a negation.
+func (m *Matcher) decide(repoRoot, absPath string, isDir bool) *Pattern {
+	var last *Pattern
+
+	for _, rules := range m.rulesFor(repoRoot) {
+		if pattern := rules.Match(absPath, isDir); pattern != nil {
+			last = pattern
+		}
+	}
+
+	relDir, err := filepath.Rel(repoRoot, filepath.Dir(absPath))
+	if err != nil {
+		return nil
+	}
+	dirs := []string{repoRoot}
+	if relDir != "." {
+		dir := repoRoot
+		for _, component := range strings.Split(relDir, string(filepath.Separator)) {
+			dir = filepath.Join(dir, component)
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, dir := range dirs {
+		if pattern := m.gitignoreIn(dir).Match(absPath, isDir); pattern != nil {
+			last = pattern
+		}
+	}
+
+	return last
+}
+
+// repoRoot returns the nearest directory at or above dir that contains .git.
+// Every directory on the way up is cached, so siblings share the lookup.
+func (m *Matcher) repoRoot(dir string) string {
+	return m.repoRoots.get(dir, func() string {
+		if _, err := m.fsys.Stat(filepath.Join(dir, gitDir)); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		return m.repoRoot(parent)
+	})
+}
+
+// rulesFor returns the repository-wide rule sets: the global excludes file
+// and .git/info/exclude, both relative to the repository root.
+func (m *Matcher) rulesFor(repoRoot string) []*Rules {
+	return m.repoRules.get(repoRoot, func() []*Rules {
+		var rules []*Rules
+		if global := m.globalExcludes(); global != nil {
+			rules = append(rules, &Rules{Base: repoRoot, Patterns: global.Patterns})
+		}
+
+		infoExclude := filepath.Join(repoRoot, gitDir, "info", "exclude")
+		if data, err := m.fsys.ReadFile(infoExclude); err == nil {
+			parsed, _ := ParseRules(data, repoRoot, infoExclude)
+			rules = append(rules, parsed)
+		}
+		return rules
+	})
+}
+
+func (m *Matcher) gitignoreIn(dir string) *Rules {
+	return m.dirRules.get(dir, func() *Rules {
+		var rules *Rules
+		path := filepath.Join(dir, gitignoreFile)
+		if data, err := m.fsys.ReadFile(path); err == nil {
+			rules, _ = ParseRules(data, dir, path)
+		}
+		return rules
+	})
+}
+
+func (m *Matcher) globalExcludes() *Rules {
+	m.globalOnce.Do(func() {
+		path := m.globalExcludesPath()
+		if path == "" {
+			return
+		}
+		if data, err := m.fsys.ReadFile(path); err == nil {
+			m.global, _ = ParseRules(data, "", path)
+		}
+	})
+	return m.global
+}
+
+// globalExcludesPath resolves core.excludesFile from the user's git
+// configuration, falling back to git's default $XDG_CONFIG_HOME/git/ignore.
+func (m *Matcher) globalExcludesPath() string {
+	home, _ := m.fsys.UserHomeDir()
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" && home != "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	var candidates []string
+	if configHome != "" {
+		candidates = append(candidates, filepath.Join(configHome, "git", "config"))
+	}
+	if home != "" {
+		candidates = append(candidates, filepath.Join(home, ".gitconfig"))
+	}
+
+	// Later files override earlier ones, as in git.
+	excludesFile := ""
+	for _, candidate := range candidates {
+		data, err := m.fsys.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		if value := coreExcludesFile(data); value != "" {
+			excludesFile = value
+		}
+	}
+
+	if excludesFile == "" {
+		if configHome == "" {
+			return ""
+		}
+		return filepath.Join(configHome, "git", "ignore")
+	}
+	if strings.HasPrefix(excludesFile, "~") && home != "" {
+		excludesFile = filepath.Join(home, excludesFile[1:])
+	}
+	return excludesFile
+}
+
+// coreExcludesFile extracts core.excludesFile from a git config file. Only
+// the plain `key = value` form inside a [core] section is understood.
+func coreExcludesFile(data []byte) string {
+	value := ""
+	inCore := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section := strings.ToLower(strings.Trim(line, "[] \t"))
+			inCore = section == "core"
+			continue
+		}
+		if !inCore {
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "excludesfile") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(val), `"`)
+	}
+
+	return value
+}
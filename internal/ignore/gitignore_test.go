@@ -0,0 +1,74 @@
+package ignore_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
+)
+
+// TestMatcherConcurrent asks one matcher about many paths from several
+// goroutines at once, as the parallel walker does; run it with -race. The
+// answers must be the same as those of a matcher used from one goroutine.
+func TestMatcherConcurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	files := map[string]string{
+		".git/HEAD":  "ref: refs/heads/main\n",
+		".gitignore": "*.log\nbuild/\n",
+	}
+	var paths []string
+	for i := range 20 {
+		dir := fmt.Sprintf("pkg%d", i)
+		files[dir+"/.gitignore"] = "!keep.log\n"
+		for _, name := range []string{"main.go", "debug.log", "keep.log", "build/out.go"} {
+			files[dir+"/"+name] = "x\n"
+			paths = append(paths, filepath.Join(root, dir, name))
+		}
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fsys := fs.NewOSFileSystem()
+	want := make([]bool, len(paths))
+	sequential := ignore.NewMatcher(fsys)
+	for i, path := range paths {
+		want[i] = sequential.Match(path, false)
+	}
+
+	matcher := ignore.NewMatcher(fsys)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, path := range paths {
+				if got := matcher.Match(path, false); got != want[i] {
+					t.Errorf("Match(%s) = %v, want %v", path, got, want[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, path := range paths {
+		rel, _ := filepath.Rel(root, path)
+		ignored := filepath.Base(path) == "debug.log" || filepath.Base(filepath.Dir(path)) == "build"
+		if want[i] != ignored {
+			t.Errorf("Match(%s) = %v, want %v", rel, want[i], ignored)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package ignore
+
+import (
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// memo caches a value per key. Lookups never block each other, values of
+// different keys are loaded in parallel and concurrent loads of the same key
+// share one call, so slow loads such as reading a file happen without a lock
+// and only once.
+type memo[V any] struct {
+	values sync.Map
+	group  singleflight.Group
+}
+
+func (c *memo[V]) get(key string, load func() V) V {
+	if value, ok := c.values.Load(key); ok {
+		return value.(V)
+	}
+	value, _, _ := c.group.Do(key, func() (any, error) {
+		// Another call may have stored it between the lookup and Do.
+		if value, ok := c.values.Load(key); ok {
+			return value, nil
+		}
+		value := load()
+		c.values.Store(key, value)
+		return value, nil
+	})
+	return value.(V)
+}
@@ -0,0 +1,226 @@
+package ignore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a single line of a gitignore-style file.
+type Pattern struct {
+	Text     string
+	Source   string
+	Line     int
+	Negate   bool
+	DirOnly  bool
+	anchored bool
+	re       *regexp.Regexp
+	// kind and literal describe the shapes matched without the regexp:
+	// a plain name such as "node_modules" and a suffix such as "*.png",
+	// both unanchored. folded patterns keep literal in lower case.
+	kind    patternKind
+	literal string
+	folded  bool
+}
+
+type patternKind int
+
+const (
+	kindGlob patternKind = iota
+	// kindName matches a path whose last element is literal.
+	kindName
+	// kindSuffix matches a path whose last element ends in literal, which
+	// starts with a dot.
+	kindSuffix
+)
+
+// ParsePattern parses one line using gitignore syntax: '#' starts a comment,
+// '!' negates, a trailing '/' matches directories only, a '/' at the start or
+// in the middle anchors the pattern to the directory of the file, and '*',
+// '?', '[...]' and '**' behave as in git. Blank lines and comments return nil.
+func ParsePattern(line, source string, lineNo int) (*Pattern, error) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	p := &Pattern{Text: line, Source: source, Line: lineNo}
+
+	if strings.HasPrefix(line, "!") {
+		p.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") && !strings.HasSuffix(line, `\/`) {
+		p.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil, fmt.Errorf("%s: empty pattern %q", location(source, lineNo), p.Text)
+	}
+
+	if strings.HasPrefix(line, "/") {
+		p.anchored = true
+		line = strings.TrimLeft(line, "/")
+	} else if strings.Contains(line, "/") {
+		p.anchored = true
+	}
+
+	re, err := compileGlob(line, p.anchored)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid pattern %q: %w", location(source, lineNo), p.Text, err)
+	}
+	p.re = re
+	if !p.anchored {
+		switch {
+		case !strings.ContainsAny(line, globChars):
+			p.kind, p.literal = kindName, line
+		case strings.HasPrefix(line, "*.") && !strings.ContainsAny(line[1:], globChars):
+			p.kind, p.literal = kindSuffix, line[1:]
+		}
+	}
+
+	return p, nil
+}
+
+// globChars are the characters that make a pattern more than a plain name.
+const globChars = `*?[\`
+
+// Match reports whether the slash-separated path, relative to the directory
+// the pattern was defined in, matches the pattern.
+func (p *Pattern) Match(relPath string, isDir bool) bool {
+	if p.DirOnly && !isDir {
+		return false
+	}
+	if p.kind == kindGlob {
+		return p.re.MatchString(relPath)
+	}
+
+	name := relPath[strings.LastIndexByte(relPath, '/')+1:]
+	if p.folded {
+		name = strings.ToLower(name)
+	}
+	if p.kind == kindName {
+		return name == p.literal
+	}
+	return strings.HasSuffix(name, p.literal)
+}
+
+// FoldCase returns a copy of the pattern that matches regardless of case.
+func (p *Pattern) FoldCase() *Pattern {
+	folded := *p
+	folded.re = regexp.MustCompile("(?i)" + p.re.String())
+	folded.literal = strings.ToLower(p.literal)
+	folded.folded = true
+	return &folded
+}
+
+func (p *Pattern) String() string {
+	if p.Source == "" {
+		return p.Text
+	}
+	return fmt.Sprintf("%s: %s", location(p.Source, p.Line), p.Text)
+}
+
+// location formats "source:line", leaving out the line for patterns that do
+// not come from a file, such as flags.
+func location(source string, lineNo int) string {
+	if lineNo == 0 {
+		return source
+	}
+	return fmt.Sprintf("%s:%d", source, lineNo)
+}
+
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// compileGlob translates a gitignore glob into a regular expression. An
+// unanchored pattern may match at any depth, like a leading "**/".
+func compileGlob(glob string, anchored bool) (*regexp.Regexp, error) {
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			atStart := i == 0 || glob[i-1] == '/'
+			rest := glob[i+2:]
+			switch {
+			case atStart && strings.HasPrefix(rest, "/"):
+				// "**/" matches zero or more leading directories.
+				re.WriteString("(?:.*/)?")
+				i += 2
+			case atStart && rest == "":
+				// A trailing "/**" matches everything inside.
+				re.WriteString(".*")
+				i++
+			default:
+				// Any other "**" is an ordinary "*".
+				re.WriteString("[^/]*")
+				i++
+			}
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end, class := parseClass(glob[i:])
+			if end < 0 {
+				re.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			re.WriteString(class)
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// parseClass converts a bracket expression starting at glob[0] and returns
+// the index of its closing ']' together with the regexp class, or -1 if the
+// bracket is not closed.
+func parseClass(glob string) (int, string) {
+	i := 1
+	var class strings.Builder
+	class.WriteString("[")
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		// A negated class never matches the path separator.
+		class.WriteString("^/")
+		i++
+	}
+	if i < len(glob) && glob[i] == ']' {
+		class.WriteString(`\]`)
+		i++
+	}
+	for ; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case ']':
+			class.WriteString("]")
+			return i, class.String()
+		case '\\', '[':
+			class.WriteString(`\`)
+			class.WriteByte(c)
+		default:
+			class.WriteByte(c)
+		}
+	}
+	return -1, ""
+}
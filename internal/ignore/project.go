@@ -0,0 +1,50 @@
+package ignore
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	ProjectIgnoreFile  = ".getctxignore"
+	ProjectIncludeFile = ".getctxinclude"
+)
+
+// LoadProjectRules reads every file called name in startPath and its parent
+// directories, stopping at the repository root (the first directory that
+// contains .git) or at the filesystem root. The rule sets are returned
+// outermost first, so files closer to startPath take precedence.
+func LoadProjectRules(fsys FileSystem, startPath, name string) ([]*Rules, error) {
+	dir, err := fsys.Abs(startPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", startPath, err)
+	}
+	if info, err := fsys.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	var found []*Rules
+	for {
+		path := filepath.Join(dir, name)
+		if data, err := fsys.ReadFile(path); err == nil {
+			rules, err := ParseRules(data, dir, path)
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, rules)
+		}
+
+		if _, err := fsys.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	slices.Reverse(found)
+	return found, nil
+}
@@ -0,0 +1,174 @@
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Rules is an ordered list of patterns read from one file. Patterns are
+// matched against paths relative to Base; the last matching pattern wins.
+// Unbounded rules also apply outside Base, where paths are matched as seen
+// from the filesystem root, so in practice only unanchored patterns match.
+type Rules struct {
+	Base      string
+	Patterns  []*Pattern
+	Unbounded bool
+
+	index atomic.Pointer[ruleIndex]
+}
+
+// Clone returns a copy whose Patterns can be changed without affecting r.
+func (r *Rules) Clone() *Rules {
+	return &Rules{Base: r.Base, Patterns: slices.Clone(r.Patterns), Unbounded: r.Unbounded}
+}
+
+// ParseRules reads gitignore-style patterns from data. Invalid lines are
+// skipped and reported together in the returned error, so one typo does not
+// disable the whole file.
+func ParseRules(data []byte, base, source string) (*Rules, error) {
+	rules := &Rules{Base: base}
+	var errs []error
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		pattern, err := ParsePattern(scanner.Text(), source, lineNo)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if pattern != nil {
+			rules.Patterns = append(rules.Patterns, pattern)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return rules, errors.Join(errs...)
+}
+
+// Match returns the last pattern matching absPath, or nil. Paths outside
+// Base only match unbounded rules.
+func (r *Rules) Match(absPath string, isDir bool) *Pattern {
+	if r == nil || len(r.Patterns) == 0 {
+		return nil
+	}
+
+	relPath, ok := r.relative(absPath)
+	if !ok {
+		return nil
+	}
+
+	index := r.index.Load()
+	if index == nil || !slices.Equal(index.patterns, r.Patterns) {
+		index = newRuleIndex(r.Patterns)
+		r.index.Store(index)
+	}
+
+	// The indexed patterns give the last plain match; only globs after it
+	// can still win.
+	best := index.match(relPath, isDir)
+	for i := len(index.globs) - 1; i >= 0 && index.globs[i] > best; i-- {
+		if r.Patterns[index.globs[i]].Match(relPath, isDir) {
+			best = index.globs[i]
+			break
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	return r.Patterns[best]
+}
+
+// ruleIndex finds the plain patterns that match a path by map lookups
+// instead of trying them one by one: names by the last element of the path
+// and suffixes by every ending of it that starts with a dot. Case-folded
+// patterns are kept apart and looked up in lower case. Everything else is a
+// glob, listed by position.
+type ruleIndex struct {
+	// patterns are the Patterns the index was built from; Rules rebuilds it
+	// when they change.
+	patterns []*Pattern
+	names    [2]map[string][]int
+	suffixes [2]map[string][]int
+	globs    []int
+}
+
+func newRuleIndex(patterns []*Pattern) *ruleIndex {
+	index := &ruleIndex{patterns: slices.Clone(patterns)}
+	for i := range index.names {
+		index.names[i] = make(map[string][]int)
+		index.suffixes[i] = make(map[string][]int)
+	}
+	for i, pattern := range patterns {
+		fold := 0
+		if pattern.folded {
+			fold = 1
+		}
+		switch pattern.kind {
+		case kindName:
+			index.names[fold][pattern.literal] = append(index.names[fold][pattern.literal], i)
+		case kindSuffix:
+			index.suffixes[fold][pattern.literal] = append(index.suffixes[fold][pattern.literal], i)
+		default:
+			index.globs = append(index.globs, i)
+		}
+	}
+	return index
+}
+
+// match returns the position of the last plain pattern matching relPath, or
+// -1.
+func (x *ruleIndex) match(relPath string, isDir bool) int {
+	best := -1
+	consider := func(positions []int) {
+		for _, i := range positions {
+			if i > best && (!x.patterns[i].DirOnly || isDir) {
+				best = i
+			}
+		}
+	}
+
+	name := relPath[strings.LastIndexByte(relPath, '/')+1:]
+	lower := strings.ToLower(name)
+	consider(x.names[0][name])
+	consider(x.names[1][lower])
+	for i := range len(name) {
+		if name[i] == '.' {
+			consider(x.suffixes[0][name[i:]])
+		}
+	}
+	for i := range len(lower) {
+		if lower[i] == '.' {
+			consider(x.suffixes[1][lower[i:]])
+		}
+	}
+	return best
+}
+
+// Covers reports whether absPath lies below Base.
+func (r *Rules) Covers(absPath string) bool {
+	_, ok := r.relative(absPath)
+	return ok
+}
+
+func (r *Rules) relative(absPath string) (string, bool) {
+	relPath, err := filepath.Rel(r.Base, absPath)
+	if err != nil || r.Base == "" || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		if !r.Unbounded {
+			return "", false
+		}
+		relPath = strings.TrimLeft(absPath[len(filepath.VolumeName(absPath)):], string(filepath.Separator))
+	}
+	if relPath == "." || relPath == "" {
+		return "", false
+	}
+	return filepath.ToSlash(relPath), true
+}
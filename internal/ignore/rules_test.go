@@ -0,0 +1,76 @@
+package ignore
+
+import "testing"
+
+// TestRulesMatchIndex checks that the lookups for plain names and suffixes
+// pick the same pattern as trying every pattern's regexp from the last one.
+func TestRulesMatchIndex(t *testing.T) {
+	lines := []string{
+		"node_modules", "*.png", "*.min.js", "build/", "docs/*.md", "!keep.png",
+		"*.log", "!important.log", "tmp*", "[Mm]akefile", "/root.txt", "*.",
+		"vendor", "!vendor/", "**/gen/**", "a?c", `\#hash`, "*.PNG",
+	}
+	rules := &Rules{Base: "/repo"}
+	for i, line := range lines {
+		pattern, err := ParsePattern(line, "test", i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "*.png" {
+			pattern = pattern.FoldCase()
+		}
+		rules.Patterns = append(rules.Patterns, pattern)
+	}
+
+	paths := []string{
+		"node_modules", "src/node_modules", "src/node_modules.go", "logo.png", "img/LOGO.PNG",
+		"img/keep.png", "img/Keep.png", "app.min.js", "app.js", ".min.js", "build", "src/build",
+		"docs/a.md", "x/docs/a.md", "debug.log", "important.log", "tmpfile", "Makefile", "makefile",
+		"root.txt", "sub/root.txt", "name.", "vendor", "x/vendor", "gen/a.go", "x/gen/y/z.go",
+		"abc", "a/b", "#hash", "x.PNG", "x.Png",
+	}
+	for _, path := range paths {
+		for _, isDir := range []bool{false, true} {
+			var want *Pattern
+			for i := len(rules.Patterns) - 1; i >= 0; i-- {
+				p := rules.Patterns[i]
+				if (!p.DirOnly || isDir) && p.re.MatchString(path) {
+					want = p
+					break
+				}
+			}
+			if got := rules.Match("/repo/"+path, isDir); got != want {
+				t.Errorf("Match(%s, dir=%v) = %v, want %v", path, isDir, got, want)
+			}
+		}
+	}
+}
+
+func TestPatternKinds(t *testing.T) {
+	tests := []struct {
+		line string
+		kind patternKind
+	}{
+		{"node_modules", kindName},
+		{".DS_Store", kindName},
+		{"dist/", kindName},
+		{"*.png", kindSuffix},
+		{"*.min.js", kindSuffix},
+		{"/root.txt", kindGlob},
+		{"docs/*.md", kindGlob},
+		{"*.[ch]", kindGlob},
+		{"tmp*", kindGlob},
+		{"**/gen", kindGlob},
+		{`\#hash`, kindName},
+		{`a\*b`, kindGlob},
+	}
+	for _, tt := range tests {
+		pattern, err := ParsePattern(tt.line, "test", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pattern.kind != tt.kind {
+			t.Errorf("kind of %q = %d, want %d", tt.line, pattern.kind, tt.kind)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package selection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+)
+
+// Modes of a list entry: a single file, or a directory whose files are all
+// selected.
+const (
+	ModeFile = "file"
+	ModeDir  = "dir"
+)
+
+const (
+	FormatPlain = "plain"
+	FormatJSON  = "json"
+)
+
+const listVersion = 1
+
+// Entry is one selected path. Paths inside the project are relative to its
+// root and use forward slashes, so a list works in any checkout.
+type Entry struct {
+	Path string `json:"path"`
+	Mode string `json:"mode,omitempty"`
+}
+
+// List is a shareable selection.
+type List struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"paths"`
+}
+
+// New builds a sorted list from paths, making the ones below root relative to
+// it. Paths that can not be stat'ed are listed as files.
+func New(fsys fs.FileSystem, root string, paths []string) *List {
+	list := &List{Version: listVersion, Entries: make([]Entry, 0, len(paths))}
+	for _, path := range paths {
+		if absPath, err := fsys.Abs(path); err == nil {
+			path = absPath
+		}
+		mode := ModeFile
+		if info, err := fsys.Stat(path); err == nil && info.IsDir() {
+			mode = ModeDir
+		}
+		list.Entries = append(list.Entries, Entry{Path: fs.RelativeTo(root, path), Mode: mode})
+	}
+	slices.SortFunc(list.Entries, func(a, b Entry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return list
+}
+
+// FormatFor picks the format of a list file from its extension: JSON for
+// .json files, the plain path list otherwise.
+func FormatFor(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return FormatJSON
+	}
+	return FormatPlain
+}
+
+// Write writes the list as JSON or as a plain newline-separated path list,
+// which is what `getctx -` and --from-file read as well.
+func (l *List) Write(w io.Writer, format string) error {
+	if format == FormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(l)
+	}
+
+	var b strings.Builder
+	for _, entry := range l.Entries {
+		b.WriteString(entry.Path)
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Save writes the list to path in the format its extension implies.
+func (l *List) Save(fsys fs.FileSystem, path string) error {
+	file, err := fs.CreateAtomic(fsys, path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer file.Abort()
+	if err := l.Write(file, FormatFor(path)); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load reads the list stored at path.
+func Load(fsys fs.FileSystem, path string) (*List, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open selection list %s: %w", path, err)
+	}
+	defer file.Close()
+
+	list, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return list, nil
+}
+
+// Read parses a list in either format. JSON is recognized by its opening
+// brace; anything else is a newline- or NUL-separated path list.
+func Read(r io.Reader) (*List, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read path list: %w", err)
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		paths, err := fs.ReadPathList(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		list := &List{Version: listVersion, Entries: make([]Entry, len(paths))}
+		for i, path := range paths {
+			list.Entries[i] = Entry{Path: path}
+		}
+		return list, nil
+	}
+
+	var list List
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&list); err != nil {
+		return nil, fmt.Errorf("invalid selection list: %w", err)
+	}
+	if list.Version > listVersion {
+		return nil, fmt.Errorf("unsupported selection list version %d", list.Version)
+	}
+	for _, entry := range list.Entries {
+		if entry.Path == "" {
+			return nil, fmt.Errorf("invalid selection list: empty path")
+		}
+		if entry.Mode != "" && entry.Mode != ModeFile && entry.Mode != ModeDir {
+			return nil, fmt.Errorf("invalid selection list: unknown mode %q for %s (use %q or %q)", entry.Mode, entry.Path, ModeFile, ModeDir)
+		}
+	}
+	return &list, nil
+}
+
+// Paths returns the entries with relative paths joined to base. An empty
+// base leaves them relative to the working directory.
+func (l *List) Paths(base string) []string {
+	paths := make([]string, len(l.Entries))
+	for i, entry := range l.Entries {
+		path := filepath.FromSlash(entry.Path)
+		if base != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+		paths[i] = path
+	}
+	return paths
+}
+
+// Resolve is Paths followed by a check that every entry still exists and is
+// still what its mode says. Entries that fail it are returned separately, as
+// written in the list.
+func (l *List) Resolve(fsys fs.FileSystem, base string) (paths, missing []string) {
+	for i, path := range l.Paths(base) {
+		if info, err := fsys.Stat(path); err != nil || !l.Entries[i].matches(info) {
+			missing = append(missing, l.Entries[i].Path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, missing
+}
+
+// CheckModes reports the first entry that exists but is a file where the list
+// says directory or the other way round. Missing entries are not checked.
+func (l *List) CheckModes(fsys fs.FileSystem, base string) error {
+	for i, path := range l.Paths(base) {
+		entry := l.Entries[i]
+		if info, err := fsys.Stat(path); err == nil && !entry.matches(info) {
+			return fmt.Errorf("%s is listed as a %s but is not one", entry.Path, modeNames[entry.Mode])
+		}
+	}
+	return nil
+}
+
+var modeNames = map[string]string{ModeFile: "file", ModeDir: "directory"}
+
+// matches reports whether info agrees with the mode of the entry. An entry
+// without a mode, as in a plain list, matches anything.
+func (e Entry) matches(info iofs.FileInfo) bool {
+	switch e.Mode {
+	case ModeFile:
+		return !info.IsDir()
+	case ModeDir:
+		return info.IsDir()
+	}
+	return true
+}
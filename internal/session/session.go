@@ -0,0 +1,151 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+)
+
+const (
+	appName     = "getctx"
+	sessionsDir = "sessions"
+)
+
+// Session is what the file browser remembers about a project between runs.
+// Sessions are keyed by the absolute Root, so a moved checkout starts afresh;
+// paths inside the project are stored relative to it.
+type Session struct {
+	Root     string    `json:"root"`
+	Path     string    `json:"path"`
+	Cursor   int       `json:"cursor"`
+	Selected []string  `json:"selected"`
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+// Store reads and writes sessions under $XDG_STATE_HOME/getctx/sessions,
+// one file per project root.
+type Store struct {
+	fsys fs.FileSystem
+	dir  string
+}
+
+// NewStore returns a store in the user's state directory, falling back to
+// ~/.local/state when XDG_STATE_HOME is unset.
+func NewStore(fsys fs.FileSystem) (*Store, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := fsys.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("could not determine the state directory: %w", err)
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return &Store{fsys: fsys, dir: filepath.Join(stateHome, appName, sessionsDir)}, nil
+}
+
+// Path returns the session file of a project root.
+func (s *Store) Path(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load returns the saved session of a project root, or nil if there is none.
+func (s *Store) Load(root string) (*Session, error) {
+	path := s.Path(root)
+	data, err := s.fsys.ReadFile(path)
+	if errors.Is(err, iofs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// Two roots whose hashes collide must not see each other's sessions.
+	if session.Root != root {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+// Save writes the session of its root, replacing the previous one.
+func (s *Store) Save(session *Session) error {
+	if err := s.fsys.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("could not create %s: %w", s.dir, err)
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// A session cut short by a crash would fail to decode on every launch,
+	// so the previous one is only replaced by a complete file.
+	path := s.Path(session.Root)
+	file, err := fs.CreateAtomic(s.fsys, path)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", path, err)
+	}
+	defer file.Abort()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	return nil
+}
+
+// New records the state of the file browser. Absolute paths below root are
+// made relative to it.
+func New(root, path string, cursor int, selected []string) *Session {
+	session := &Session{
+		Root:     root,
+		Path:     fs.RelativeTo(root, path),
+		Cursor:   cursor,
+		Selected: make([]string, 0, len(selected)),
+		SavedAt:  time.Now(),
+	}
+	for _, selectedPath := range selected {
+		session.Selected = append(session.Selected, fs.RelativeTo(root, selectedPath))
+	}
+	return session
+}
+
+// Resolve turns the paths of the session back into absolute ones and drops
+// the selected paths that no longer exist, returning them separately. A
+// directory that is gone is replaced by root.
+func (s *Session) Resolve(fsys fs.FileSystem) (path string, selected, stale []string) {
+	path = s.absolute(s.Path)
+	if info, err := fsys.Stat(path); err != nil || !info.IsDir() {
+		path = s.Root
+	}
+
+	for _, selectedPath := range s.Selected {
+		absPath := s.absolute(selectedPath)
+		if _, err := fsys.Stat(absPath); err != nil {
+			stale = append(stale, selectedPath)
+			continue
+		}
+		selected = append(selected, absPath)
+	}
+	return path, selected, stale
+}
+
+func (s *Session) absolute(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(path))
+}
@@ -0,0 +1,289 @@
+package tokens
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxCacheEntries bounds the per-piece merge cache; it is dropped wholesale
+// when full, which is cheap and keeps memory flat on huge inputs.
+const maxCacheEntries = 1 << 16
+
+// BPE counts tokens with a byte-level byte-pair encoding. The vocabulary is
+// loaded from a tiktoken-style rank file (one "<base64 token> <rank>" pair
+// per line, as in cl100k_base.tiktoken or o200k_base.tiktoken) and the text
+// is pre-split the same way those encodings do before merging.
+type BPE struct {
+	name  string
+	ranks map[string]int
+
+	mu    sync.RWMutex
+	cache map[string]int
+}
+
+func LoadBPE(name string, r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := bytes.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid vocabulary line %d: expected \"<token> <rank>\"", lineNo)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid token on vocabulary line %d: %w", lineNo, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank on vocabulary line %d: %w", lineNo, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read vocabulary: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocabulary is empty")
+	}
+
+	return &BPE{
+		name:  name,
+		ranks: ranks,
+		cache: make(map[string]int),
+	}, nil
+}
+
+func (b *BPE) Name() string {
+	return b.name
+}
+
+func (b *BPE) Count(text []byte) int {
+	count := 0
+	for _, piece := range splitPieces(text) {
+		count += b.countPiece(piece)
+	}
+	return count
+}
+
+func (b *BPE) countPiece(piece []byte) int {
+	if _, ok := b.ranks[string(piece)]; ok {
+		return 1
+	}
+
+	b.mu.RLock()
+	cached, ok := b.cache[string(piece)]
+	b.mu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	count := b.merge(piece)
+
+	b.mu.Lock()
+	if len(b.cache) >= maxCacheEntries {
+		clear(b.cache)
+	}
+	b.cache[string(piece)] = count
+	b.mu.Unlock()
+
+	return count
+}
+
+// merge runs the classic BPE loop: starting from single bytes, repeatedly
+// join the adjacent pair with the lowest rank, the leftmost on a tie, until
+// no pair is in the vocabulary. It returns the number of parts left.
+//
+// The parts form a linked list and the candidate pairs a heap, so a merge
+// only ranks the two new pairs next to it instead of rescanning the piece;
+// long runs of one character class, as in minified files, stay O(n log n).
+func (b *BPE) merge(piece []byte) int {
+	n := len(piece)
+	// A part is named by its start; next holds the start of the part after
+	// it, n for the last one.
+	next := make([]int, n)
+	prev := make([]int, n)
+	alive := make([]bool, n)
+	for i := range n {
+		next[i], prev[i], alive[i] = i+1, i-1, true
+	}
+	queue := &mergeQueue{}
+	push := func(left int) {
+		right := next[left]
+		if right == n {
+			return
+		}
+		if rank, ok := b.ranks[string(piece[left:next[right]])]; ok {
+			heap.Push(queue, mergeCandidate{rank: rank, left: left, right: right, end: next[right]})
+		}
+	}
+	for i := range n {
+		push(i)
+	}
+
+	parts := n
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(mergeCandidate)
+		// Skip pairs that an earlier merge changed.
+		if !alive[c.left] || next[c.left] != c.right || next[c.right] != c.end {
+			continue
+		}
+		alive[c.right] = false
+		next[c.left] = c.end
+		if c.end < n {
+			prev[c.end] = c.left
+		}
+		parts--
+		if prev[c.left] >= 0 {
+			push(prev[c.left])
+		}
+		push(c.left)
+	}
+	return parts
+}
+
+// mergeCandidate is a pair of adjacent parts, the left one starting at left
+// and the right one spanning right to end.
+type mergeCandidate struct {
+	rank             int
+	left, right, end int
+}
+
+// mergeQueue orders candidates by rank, then from left to right.
+type mergeQueue []mergeCandidate
+
+func (q mergeQueue) Len() int { return len(q) }
+func (q mergeQueue) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank < q[j].rank
+	}
+	return q[i].left < q[j].left
+}
+func (q mergeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *mergeQueue) Push(x any)   { *q = append(*q, x.(mergeCandidate)) }
+func (q *mergeQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// splitPieces pre-tokenizes text following the cl100k_base pattern:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Go's regexp has no lookahead, so the pattern is implemented by hand.
+func splitPieces(text []byte) [][]byte {
+	var pieces [][]byte
+	for i := 0; i < len(text); {
+		n := nextPiece(text[i:])
+		pieces = append(pieces, text[i:i+n])
+		i += n
+	}
+	return pieces
+}
+
+func nextPiece(text []byte) int {
+	r, size := utf8.DecodeRune(text)
+
+	if n := contractionLength(text); n > 0 {
+		return n
+	}
+
+	if unicode.IsLetter(r) {
+		return size + runLength(text[size:], unicode.IsLetter)
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) {
+		if next, nextSize := utf8.DecodeRune(text[size:]); size < len(text) && unicode.IsLetter(next) {
+			return size + nextSize + runLength(text[size+nextSize:], unicode.IsLetter)
+		}
+	}
+
+	if unicode.IsNumber(r) {
+		n, digits := size, 1
+		for n < len(text) && digits < 3 {
+			next, nextSize := utf8.DecodeRune(text[n:])
+			if !unicode.IsNumber(next) {
+				break
+			}
+			n += nextSize
+			digits++
+		}
+		return n
+	}
+
+	punctStart := 0
+	if r == ' ' {
+		punctStart = size
+	}
+	if punct := runLength(text[punctStart:], isPunct); punct > 0 {
+		n := punctStart + punct
+		return n + runLength(text[n:], isNewline)
+	}
+
+	if unicode.IsSpace(r) {
+		spaces := runLength(text, unicode.IsSpace)
+		if lastNewline := bytes.LastIndexAny(text[:spaces], "\r\n"); lastNewline >= 0 {
+			return lastNewline + 1
+		}
+		if spaces == len(text) || spaces == size {
+			return spaces
+		}
+		// Leave the last space so it can be merged into the next word.
+		_, lastSize := utf8.DecodeLastRune(text[:spaces])
+		return spaces - lastSize
+	}
+
+	return size
+}
+
+func contractionLength(text []byte) int {
+	if len(text) < 2 || text[0] != '\'' {
+		return 0
+	}
+	lower := bytes.ToLower(text[1:min(len(text), 3)])
+	for _, suffix := range []string{"re", "ve", "ll", "s", "t", "m", "d"} {
+		if bytes.HasPrefix(lower, []byte(suffix)) {
+			return 1 + len(suffix)
+		}
+	}
+	return 0
+}
+
+func runLength(text []byte, match func(rune) bool) int {
+	n := 0
+	for n < len(text) {
+		r, size := utf8.DecodeRune(text[n:])
+		if !match(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func isPunct(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
@@ -0,0 +1,90 @@
+package tokens
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBPE(t *testing.T, tokens ...string) *BPE {
+	t.Helper()
+	var vocab strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&vocab, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	bpe, err := LoadBPE("test", strings.NewReader(vocab.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bpe
+}
+
+// quadraticMerge is the textbook loop that rescans every pair after each
+// merge; merge must always agree with it.
+func quadraticMerge(ranks map[string]int, piece []byte) int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		bestRank, bestIndex := math.MaxInt, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			rank, ok := ranks[string(piece[bounds[i]:bounds[i+2]])]
+			if ok && rank < bestRank {
+				bestRank, bestIndex = rank, i
+			}
+		}
+		if bestIndex < 0 {
+			break
+		}
+		bounds = append(bounds[:bestIndex+1], bounds[bestIndex+2:]...)
+	}
+	return len(bounds) - 1
+}
+
+func TestMergeMatchesQuadratic(t *testing.T) {
+	bpe := testBPE(t, "ab", "ba", "aa", "abab", "bb", "aab", "ababab", "bba", "aaaa")
+	rng := rand.New(rand.NewSource(1))
+	for range 2000 {
+		piece := make([]byte, 1+rng.Intn(40))
+		for i := range piece {
+			piece[i] = "ab"[rng.Intn(2)]
+		}
+		if got, want := bpe.merge(piece), quadraticMerge(bpe.ranks, piece); got != want {
+			t.Fatalf("merge(%q) = %d, want %d", piece, got, want)
+		}
+	}
+}
+
+// TestCountLongRun counts 100 KiB runs of one character class, which are a
+// single piece each, as in minified or generated files.
+func TestCountLongRun(t *testing.T) {
+	bpe := testBPE(t, "aa", "aaaa", "aaaaaaaa", "==", "====")
+	tests := []struct {
+		name string
+		text []byte
+		want int
+	}{
+		{name: "letters", text: bytes.Repeat([]byte("a"), 100*1024), want: 100 * 1024 / 8},
+		{name: "punctuation", text: bytes.Repeat([]byte("="), 100*1024), want: 100 * 1024 / 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan int, 1)
+			go func() { done <- bpe.Count(tt.text) }()
+			select {
+			case got := <-done:
+				if got != tt.want {
+					t.Errorf("Count = %d, want %d", got, tt.want)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("Count did not finish within 10s")
+			}
+		})
+	}
+}
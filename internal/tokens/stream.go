@@ -0,0 +1,72 @@
+package tokens
+
+import "io"
+
+// streamChunkSize is how much text a Stream collects before counting it.
+const streamChunkSize = 64 * 1024
+
+// Stream counts the tokens of text written to it piece by piece, holding at
+// most two chunks in memory. Chunks are cut right after a line break that is
+// followed by a non-space character, where the counters never merge two
+// pieces into one token, so the total matches counting the text as a whole.
+// Only text without such a line break in two chunks' worth of bytes is cut
+// blindly.
+type Stream struct {
+	counter Counter
+	pending []byte
+	total   int
+}
+
+func NewStream(counter Counter) *Stream {
+	return &Stream{counter: counter}
+}
+
+func (s *Stream) Write(p []byte) (int, error) {
+	s.pending = append(s.pending, p...)
+	for len(s.pending) >= streamChunkSize {
+		cut := lastBoundary(s.pending)
+		if cut <= 0 {
+			if len(s.pending) < 2*streamChunkSize {
+				break
+			}
+			cut = len(s.pending)
+		}
+		s.total += s.counter.Count(s.pending[:cut])
+		s.pending = append(s.pending[:0], s.pending[cut:]...)
+	}
+	return len(p), nil
+}
+
+// Total counts what is still pending and returns the tokens of everything
+// written so far.
+func (s *Stream) Total() int {
+	if len(s.pending) > 0 {
+		s.total += s.counter.Count(s.pending)
+		s.pending = s.pending[:0]
+	}
+	return s.total
+}
+
+// CountReader counts the tokens of everything r yields.
+func CountReader(counter Counter, r io.Reader) (int, error) {
+	stream := NewStream(counter)
+	if _, err := io.Copy(stream, r); err != nil {
+		return 0, err
+	}
+	return stream.Total(), nil
+}
+
+// lastBoundary returns the position after the last line break that is
+// followed by a non-space character, or -1.
+func lastBoundary(text []byte) int {
+	for i := len(text) - 2; i >= 0; i-- {
+		if text[i] == '\n' && !isSpace(text[i+1]) {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\r' || b == '\t' || b == '\v' || b == '\f'
+}
@@ -0,0 +1,96 @@
+package tokens
+
+import "unicode/utf8"
+
+// Counter estimates how many model tokens a piece of text occupies.
+type Counter interface {
+	Name() string
+	Count(text []byte) int
+}
+
+// HeuristicCounter approximates BPE token counts without a vocabulary. It
+// walks the text once and prices every run of characters roughly the way
+// common byte-level BPE vocabularies split it: words of up to six letters
+// are a single token, longer identifiers cost one token per six bytes,
+// numbers are split into groups of three digits and every non-ASCII
+// character counts on its own. It tends to overestimate slightly, which is
+// the safe side for budgets.
+type HeuristicCounter struct{}
+
+func NewHeuristicCounter() *HeuristicCounter {
+	return &HeuristicCounter{}
+}
+
+func (c *HeuristicCounter) Name() string {
+	return "heuristic"
+}
+
+func (c *HeuristicCounter) Count(text []byte) int {
+	count := 0
+	for i := 0; i < len(text); {
+		b := text[i]
+		switch {
+		case b >= utf8.RuneSelf:
+			_, size := utf8.DecodeRune(text[i:])
+			count++
+			i += size
+		case isASCIILetter(b):
+			j := i + 1
+			for j < len(text) && isASCIILetter(text[j]) {
+				j++
+			}
+			count += ceilDiv(j-i, 6)
+			i = j
+		case isASCIIDigit(b):
+			j := i + 1
+			for j < len(text) && isASCIIDigit(text[j]) {
+				j++
+			}
+			count += ceilDiv(j-i, 3)
+			i = j
+		case b == ' ':
+			// A single space is merged into the following word; longer runs
+			// (indentation) become tokens of their own.
+			j := i + 1
+			for j < len(text) && text[j] == ' ' {
+				j++
+			}
+			if j-i > 1 {
+				count += ceilDiv(j-i, 8)
+			}
+			i = j
+		case b == '\n' || b == '\r' || b == '\t':
+			j := i + 1
+			for j < len(text) && (text[j] == '\n' || text[j] == '\r' || text[j] == '\t') {
+				j++
+			}
+			count += ceilDiv(j-i, 4)
+			i = j
+		default:
+			j := i + 1
+			for j < len(text) && isASCIIPunct(text[j]) {
+				j++
+			}
+			count += ceilDiv(j-i, 2)
+			i = j
+		}
+	}
+	return count
+}
+
+func isASCIILetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b == '_'
+}
+
+func isASCIIDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isASCIIPunct(b byte) bool {
+	return b < utf8.RuneSelf && !isASCIILetter(b) && !isASCIIDigit(b) &&
+		b != ' ' && b != '\n' && b != '\r' && b != '\t'
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
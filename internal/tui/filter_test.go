@@ -0,0 +1,286 @@
+package tui
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kacperzielinskidev/getctx/internal/build"
+	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/ignore"
+	"github.com/kacperzielinskidev/getctx/internal/logger"
+	"github.com/kacperzielinskidev/getctx/internal/tokens"
+)
+
+// TestBrowserAgreesWithBuild checks that the decisions of the filter, what
+// the file browser greys out and what a build includes are the same.
+func TestBrowserAgreesWithBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// start is where the project rule files are looked up from.
+		start string
+		// profile, if set, is applied like -profile.
+		profile  *config.Profile
+		selected []string
+		// want is the decision for a path relative to the project root;
+		// directories end in a slash.
+		want map[string]filter.Reason
+	}{
+		{
+			name: "built-in excludes",
+			files: map[string]string{
+				"main.go":                   "package main\n",
+				"logo.svg":                  "<svg/>\n",
+				"node_modules/lib/index.js": "module.exports = {}\n",
+				"dist/app.js":               "console.log(1)\n",
+			},
+			selected: []string{"."},
+			want: map[string]filter.Reason{
+				"main.go":       filter.Included,
+				"logo.svg":      filter.ExcludedByRule,
+				"node_modules/": filter.ExcludedByRule,
+				"dist/":         filter.ExcludedByRule,
+				".git/":         filter.ExcludedByRule,
+			},
+		},
+		{
+			name: "getctxignore and getctxinclude",
+			files: map[string]string{
+				".getctxignore":      "*.tmp\n!vendor/\n",
+				"src/.getctxinclude": "*.go\n",
+				"src/main.go":        "package main\n",
+				"src/notes.txt":      "notes\n",
+				"scratch.tmp":        "scratch\n",
+				"vendor/dep.go":      "package dep\n",
+				"README.md":          "# readme\n",
+			},
+			start:    "src",
+			selected: []string{"."},
+			want: map[string]filter.Reason{
+				"src/main.go":   filter.Included,
+				"src/notes.txt": filter.NotIncluded,
+				"scratch.tmp":   filter.ExcludedByRule,
+				"vendor/":       filter.Included,
+				"README.md":     filter.Included,
+			},
+		},
+		{
+			name: "profile includes",
+			files: map[string]string{
+				"api/handler.go":      "package api\n",
+				"api/handler_test.go": "package api\n",
+				"web/app.js":          "console.log(1)\n",
+			},
+			profile:  &config.Profile{Include: []string{"api/**"}, Exclude: []string{"*_test.go"}},
+			selected: []string{"."},
+			want: map[string]filter.Reason{
+				"api/handler.go":      filter.Included,
+				"api/handler_test.go": filter.ExcludedByRule,
+				"web/":                filter.Included,
+				"web/app.js":          filter.NotIncluded,
+			},
+		},
+		{
+			name: "gitignore",
+			files: map[string]string{
+				".gitignore": "*.log\n!keep.log\ngen/\n",
+				"app.go":     "package app\n",
+				"debug.log":  "debug\n",
+				"keep.log":   "keep\n",
+				"gen/out.go": "package gen\n",
+			},
+			selected: []string{"."},
+			want: map[string]filter.Reason{
+				"app.go":    filter.Included,
+				"debug.log": filter.Gitignored,
+				"keep.log":  filter.Included,
+				"gen/":      filter.Gitignored,
+			},
+		},
+		{
+			name: "explicitly selected path",
+			files: map[string]string{
+				"main.go":                   "package main\n",
+				"node_modules/lib/index.js": "module.exports = {}\n",
+			},
+			selected: []string{"node_modules/lib/index.js"},
+			want: map[string]filter.Reason{
+				"node_modules/":             filter.ExcludedByRule,
+				"node_modules/lib/index.js": filter.Included,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Keep the user's global git excludes out of the test.
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+			root := t.TempDir()
+			writeTree(t, root, tt.files)
+			fsys := fs.NewOSFileSystem()
+			cfg := testConfig(t, fsys, root, filepath.Join(root, tt.start), tt.profile)
+			pathFilter := filter.New(fsys, cfg)
+
+			for rel, want := range tt.want {
+				path := filepath.Join(root, rel)
+				info, err := os.Lstat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				entry := iofs.FileInfoToDirEntry(info)
+
+				if got := pathFilter.Decide(path, entry).Reason; got != want {
+					t.Errorf("Decide(%s) = %v, want %v", rel, got, want)
+				}
+				if got := listedExcluded(t, fsys, pathFilter, path); got != (want != filter.Included) {
+					t.Errorf("the browser shows %s as excluded: %v, want %v", rel, got, want != filter.Included)
+				}
+			}
+
+			selected := make([]string, len(tt.selected))
+			for i, rel := range tt.selected {
+				selected[i] = filepath.Join(root, rel)
+			}
+			builder := build.NewContextBuilder(logger.New(io.Discard, logger.LevelInfo), fsys, cfg, tokens.NewHeuristicCounter())
+			result, err := builder.Build(selected, cfg.OutputFilename)
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+
+			var built []string
+			for _, file := range result.Files {
+				built = append(built, file.Path)
+			}
+			browsed := browserSelection(t, fsys, pathFilter, selected)
+			sort.Strings(built)
+			sort.Strings(browsed)
+			if strings.Join(built, "\n") != strings.Join(browsed, "\n") {
+				t.Errorf("the build includes\n%s\nbut the browser offers\n%s", strings.Join(built, "\n"), strings.Join(browsed, "\n"))
+			}
+			for rel, want := range tt.want {
+				if strings.HasSuffix(rel, "/") {
+					continue
+				}
+				path := filepath.Join(root, rel)
+				included := contains(built, path)
+				if included != (want == filter.Included) {
+					t.Errorf("the build includes %s: %v, want %v", rel, included, want == filter.Included)
+				}
+			}
+		})
+	}
+}
+
+// testConfig sets up the configuration the way the command line does for a
+// project at root, with the output file kept outside of it.
+func testConfig(t *testing.T, fsys fs.FileSystem, root, start string, profile *config.Profile) *config.Config {
+	t.Helper()
+	cfg := config.NewConfig()
+	cfg.Exclude.Base = root
+	cfg.OutputFilename = filepath.Join(t.TempDir(), config.DefaultOutputFilename)
+
+	var err error
+	if cfg.IgnoreRules, err = ignore.LoadProjectRules(fsys, start, ignore.ProjectIgnoreFile); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IncludeRules, err = ignore.LoadProjectRules(fsys, start, ignore.ProjectIncludeFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if profile != nil {
+		cfg.Profiles["test"] = *profile
+		if cfg, err = cfg.WithProfile("test", "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+// writeTree creates the files below root, and a .git directory to make it a
+// repository.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	files[".git/HEAD"] = "ref: refs/heads/main\n"
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listedExcluded reports whether the browser greys out path in the listing
+// of its directory.
+func listedExcluded(t *testing.T, fsys fs.FileSystem, pathFilter *filter.Filter, path string) bool {
+	t.Helper()
+	items, err := loadListItems(fsys, filepath.Dir(path), pathFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if item.name == filepath.Base(path) {
+			return item.isExcluded
+		}
+	}
+	t.Fatalf("%s is not listed", path)
+	return false
+}
+
+// browserSelection returns the files that selecting paths in the browser
+// offers: a selected file if it is not greyed out, and everything below a
+// selected directory that can be reached without entering a greyed out one.
+func browserSelection(t *testing.T, fsys fs.FileSystem, pathFilter *filter.Filter, paths []string) []string {
+	t.Helper()
+	var files []string
+	var walk func(dir string)
+	walk = func(dir string) {
+		items, err := loadListItems(fsys, dir, pathFilter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			path := filepath.Join(dir, item.name)
+			switch {
+			case item.isExcluded:
+			case item.isDir:
+				walk(path)
+			default:
+				files = append(files, path)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.IsDir() {
+			walk(path)
+		} else if !listedExcluded(t, fsys, pathFilter, path) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+func contains(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,13 @@
 package tui
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
 const (
 	KeyCtrlC     = "ctrl+c"
 	KeyQ         = "q"
@@ -12,7 +20,200 @@ const (
 	KeyCtrlHome  = "ctrl+home"
 	KeyCtrlEnd   = "ctrl+end"
 	KeyP         = "ctrl+p"
+	KeyCtrlO     = "ctrl+o"
+	KeyCtrlE     = "ctrl+e"
+	KeyCtrlR     = "ctrl+r"
 	KeyEscape    = "esc"
 	KeySlash     = "/"
 	KeyTab       = "tab"
 )
+
+// action is a command of the file browser that can be bound to keys.
+type action string
+
+const (
+	actionUp          action = "up"
+	actionDown        action = "down"
+	actionTop         action = "top"
+	actionBottom      action = "bottom"
+	actionOpen        action = "open"
+	actionParent      action = "parent"
+	actionSelect      action = "select"
+	actionSelectAll   action = "select_all"
+	actionFilter      action = "filter"
+	actionFindPath    action = "find_path"
+	actionClearFilter action = "clear_filter"
+	actionProfiles    action = "profiles"
+	actionExport      action = "export"
+	actionImport      action = "import"
+	actionSave        action = "save"
+	actionQuit        action = "quit"
+)
+
+var defaultKeyBindings = map[action][]string{
+	actionUp:          {KeyUp},
+	actionDown:        {KeyDown},
+	actionTop:         {KeyCtrlHome},
+	actionBottom:      {KeyCtrlEnd},
+	actionOpen:        {KeyEnter},
+	actionParent:      {KeyBackspace},
+	actionSelect:      {KeySpace},
+	actionSelectAll:   {KeyCtrlA},
+	actionFilter:      {KeySlash},
+	actionFindPath:    {KeyP},
+	actionClearFilter: {KeyEscape},
+	actionProfiles:    {KeyCtrlO},
+	actionExport:      {KeyCtrlE},
+	actionImport:      {KeyCtrlR},
+	actionSave:        {KeyQ},
+	actionQuit:        {KeyCtrlC},
+}
+
+// helpActions are listed in the help header, in this order.
+var helpActions = []struct {
+	action action
+	label  string
+}{
+	{actionSelect, "select"},
+	{actionSelectAll, "select all"},
+	{actionFindPath, "find path"},
+	{actionFilter, "filter"},
+	{actionClearFilter, "clear filters"},
+	{actionProfiles, "profiles"},
+	{actionExport, "export"},
+	{actionImport, "import"},
+	{actionSave, "save"},
+	{actionQuit, "quit"},
+}
+
+type keyMap struct {
+	keys    map[action][]string
+	actions map[string]action
+}
+
+// newKeyMap applies the configured bindings on top of the defaults. The
+// bindings must have passed ValidateKeyBindings.
+func newKeyMap(bindings map[string][]string) keyMap {
+	km := keyMap{
+		keys:    make(map[action][]string, len(defaultKeyBindings)),
+		actions: make(map[string]action),
+	}
+	for act, keys := range defaultKeyBindings {
+		km.keys[act] = keys
+	}
+	for name, keys := range bindings {
+		normalized := make([]string, len(keys))
+		for i, key := range keys {
+			normalized[i] = normalizeKey(key)
+		}
+		km.keys[action(name)] = normalized
+	}
+
+	for act, keys := range km.keys {
+		for _, key := range keys {
+			km.actions[key] = act
+		}
+	}
+	return km
+}
+
+// without unbinds an action, hiding it from the help header as well.
+func (km keyMap) without(act action) keyMap {
+	for _, key := range km.keys[act] {
+		delete(km.actions, key)
+	}
+	delete(km.keys, act)
+	return km
+}
+
+func (km keyMap) action(key string) action {
+	return km.actions[key]
+}
+
+func (km keyMap) saveKey() string {
+	return displayKey(km.keys[actionSave][0])
+}
+
+// help describes the bound keys, e.g. "space: select, q: save".
+func (km keyMap) help() string {
+	parts := make([]string, 0, len(helpActions))
+	for _, entry := range helpActions {
+		keys := km.keys[entry.action]
+		if len(keys) == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", displayKey(keys[0]), entry.label))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ValidateKeyBindings reports unknown actions, empty bindings and keys that
+// would end up bound to more than one action.
+func ValidateKeyBindings(bindings map[string][]string) error {
+	var errs []error
+
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := defaultKeyBindings[action(name)]; !ok {
+			errs = append(errs, fmt.Errorf("unknown key binding action %q (known actions: %s)", name, strings.Join(knownActions(), ", ")))
+			continue
+		}
+		if len(bindings[name]) == 0 || slices.Contains(bindings[name], "") {
+			errs = append(errs, fmt.Errorf("key binding %q must list at least one non-empty key", name))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	km := newKeyMap(bindings)
+	owners := make(map[string]action)
+	for _, act := range knownActionValues() {
+		for _, key := range km.keys[act] {
+			if owner, taken := owners[key]; taken {
+				errs = append(errs, fmt.Errorf("key %q is bound to both %q and %q", displayKey(key), owner, act))
+				continue
+			}
+			owners[key] = act
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func knownActionValues() []action {
+	actions := make([]action, 0, len(defaultKeyBindings))
+	for act := range defaultKeyBindings {
+		actions = append(actions, act)
+	}
+	slices.Sort(actions)
+	return actions
+}
+
+func knownActions() []string {
+	names := make([]string, 0, len(defaultKeyBindings))
+	for _, act := range knownActionValues() {
+		names = append(names, string(act))
+	}
+	return names
+}
+
+// normalizeKey accepts "space" for the space bar, the way it is written in
+// configuration files.
+func normalizeKey(key string) string {
+	if strings.EqualFold(key, "space") {
+		return KeySpace
+	}
+	return key
+}
+
+func displayKey(key string) string {
+	if key == KeySpace {
+		return "space"
+	}
+	return key
+}
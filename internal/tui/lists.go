@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+	"github.com/kacperzielinskidev/getctx/internal/selection"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultListFile is offered as the file to export to and import from.
+const defaultListFile = "selection.txt"
+
+// maxMissingListed caps how many missing entries an import names.
+const maxMissingListed = 3
+
+func (m *Model) enterListInputMode(mode tuiMode) tea.Cmd {
+	m.mode = mode
+	m.inputErrorMsg = ""
+	value := filepath.Join(m.config.Exclude.Base, defaultListFile)
+	m.textInput.SetValue(value)
+	m.textInput.SetCursor(len(value))
+	return m.textInput.Focus()
+}
+
+func (m *Model) updateListInputMode(msg tea.Msg) tea.Cmd {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case KeyEnter:
+			return m.confirmListInput()
+		case KeyEscape, KeyCtrlC:
+			m.cancelInputMode()
+			return nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return cmd
+}
+
+func (m *Model) confirmListInput() tea.Cmd {
+	path := m.resolveInputPath(m.textInput.Value())
+
+	if m.mode == modeExportInput {
+		list := selection.New(m.fsys, m.config.Exclude.Base, m.GetSelectedPaths())
+		if err := list.Save(m.fsys, path); err != nil {
+			m.inputErrorMsg = "Error: " + err.Error()
+			return nil
+		}
+		m.cancelInputMode()
+		m.notice = fmt.Sprintf("Exported %d path(s) to %s", len(list.Entries), path)
+		m.addGeneratedFile(path)
+		return m.refreshSelectionStats()
+	}
+
+	list, err := selection.Load(m.fsys, path)
+	if err != nil {
+		m.inputErrorMsg = "Error: " + err.Error()
+		return nil
+	}
+	paths, missing := list.Resolve(m.fsys, m.config.Exclude.Base)
+	m.Select(paths)
+	m.cancelInputMode()
+	m.notice = fmt.Sprintf("Imported %d path(s) from %s", len(paths), path)
+	if len(missing) > 0 {
+		m.notice += fmt.Sprintf("; %d missing: %s", len(missing), summarize(missing, maxMissingListed))
+	}
+	return m.refreshSelectionStats()
+}
+
+// summarize joins the first limit items and says how many were left out.
+func summarize(items []string, limit int) string {
+	if len(items) <= limit {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:limit], ", "), len(items)-limit)
+}
+
+// addGeneratedFile leaves a list written from the browser out of the listing,
+// the stats and the build, like the output file.
+func (m *Model) addGeneratedFile(path string) {
+	m.stopSelectionStats()
+	m.statsMu.Lock()
+	m.config.GeneratedFiles = append(m.config.GeneratedFiles, path)
+	m.statsMu.Unlock()
+	m.filter = filter.New(m.fsys, m.config)
+	m.changeDirectory(m.path)
+}
@@ -1,9 +1,14 @@
 package tui
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"sync"
 
+	"github.com/kacperzielinskidev/getctx/internal/build"
 	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/filter"
 	"github.com/kacperzielinskidev/getctx/internal/fs"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -17,6 +22,10 @@ const (
 	modeNormal tuiMode = iota
 	modePathInput
 	modeFilter
+	modeProfilePicker
+	modeRestorePrompt
+	modeExportInput
+	modeImportInput
 )
 
 type listItem struct {
@@ -28,6 +37,10 @@ type listItem struct {
 type Model struct {
 	config                *config.Config
 	fsys                  fs.FileSystem
+	builder               *build.ContextBuilder
+	filter                *filter.Filter
+	keys                  keyMap
+	helpHeader            string
 	path                  string
 	items                 []listItem
 	selected              map[string]struct{}
@@ -37,20 +50,37 @@ type Model struct {
 	completionViewport    viewport.Model
 	mode                  tuiMode
 	cursor                int
+	profileCursor         int
+	pending               *pendingSession
 	filterQuery           string
 	inputErrorMsg         string
+	notice                string
 	completionSuggestions []string
 	width                 int
 	height                int
+	selectionStats        build.Measurement
+	statsGeneration       int
+	statsPending          bool
+	cancelStats           context.CancelFunc
+	// statsMu is held for reading by a running stats job, which reads the
+	// configuration, and for writing while the configuration changes.
+	statsMu *sync.RWMutex
 }
 
-func NewModel(startPath string, config *config.Config, fsys fs.FileSystem) (*Model, error) {
+func NewModel(startPath string, config *config.Config, fsys fs.FileSystem, builder *build.ContextBuilder) (*Model, error) {
 	path, err := fsys.Abs(startPath)
 	if err != nil {
 		return nil, fmt.Errorf("could not get absolute path for '%s': %w", startPath, err)
 	}
 
-	items, err := loadListItems(fsys, path, config)
+	ApplyTheme(config.Theme)
+	keys := newKeyMap(config.KeyBindings)
+	if len(config.Profiles) == 0 {
+		keys = keys.without(actionProfiles)
+	}
+	pathFilter := filter.New(fsys, config)
+
+	items, err := loadListItems(fsys, path, pathFilter)
 	if err != nil {
 		return nil, fmt.Errorf("could not read directory '%s': %w", path, err)
 	}
@@ -62,6 +92,10 @@ func NewModel(startPath string, config *config.Config, fsys fs.FileSystem) (*Mod
 	m := &Model{
 		config:             config,
 		fsys:               fsys,
+		builder:            builder,
+		filter:             pathFilter,
+		keys:               keys,
+		helpHeader:         renderHelpHeader(keys),
 		path:               path,
 		items:              items,
 		selected:           make(map[string]struct{}),
@@ -69,13 +103,28 @@ func NewModel(startPath string, config *config.Config, fsys fs.FileSystem) (*Mod
 		viewport:           viewport.New(0, 0),
 		completionViewport: viewport.New(0, 0),
 		mode:               modeNormal,
+		statsMu:            &sync.RWMutex{},
+	}
+
+	if config.Profile != "" {
+		if err := m.selectProfilePaths(); err != nil {
+			return nil, err
+		}
 	}
 
 	return m, nil
 }
 
 func (m *Model) Init() tea.Cmd {
-	return nil
+	return m.refreshSelectionStats()
+}
+
+// Select replaces the selection with the given absolute paths.
+func (m *Model) Select(paths []string) {
+	m.selected = make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		m.selected[path] = struct{}{}
+	}
 }
 
 func (m *Model) GetSelectedPaths() []string {
@@ -87,7 +136,7 @@ func (m *Model) GetSelectedPaths() []string {
 }
 
 func (m *Model) changeDirectory(newPath string) {
-	newItems, err := loadListItems(m.fsys, newPath, m.config)
+	newItems, err := loadListItems(m.fsys, newPath, m.filter)
 	if err != nil {
 		m.inputErrorMsg = "Error reading directory: " + err.Error()
 		return
@@ -101,7 +150,7 @@ func (m *Model) changeDirectory(newPath string) {
 	m.viewport.GotoTop()
 }
 
-func loadListItems(fsys fs.FileSystem, path string, config *config.Config) ([]listItem, error) {
+func loadListItems(fsys fs.FileSystem, path string, pathFilter *filter.Filter) ([]listItem, error) {
 	dirEntries, err := fsys.ReadDir(path)
 	if err != nil {
 		return nil, err
@@ -112,7 +161,7 @@ func loadListItems(fsys fs.FileSystem, path string, config *config.Config) ([]li
 		items[i] = listItem{
 			name:       entry.Name(),
 			isDir:      entry.IsDir(),
-			isExcluded: config.IsExcluded(entry.Name()),
+			isExcluded: pathFilter.Excludes(filepath.Join(path, entry.Name()), entry),
 		}
 	}
 	return items, nil
@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/filter"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// profileSource is recorded as the origin of a profile picked in the TUI.
+const profileSource = "TUI"
+
+func (m *Model) enterProfilePickerMode() {
+	m.mode = modeProfilePicker
+	m.inputErrorMsg = ""
+	m.profileCursor = 0
+	for i, name := range m.config.ProfileNames() {
+		if name == m.config.Profile {
+			m.profileCursor = i
+		}
+	}
+	m.viewport.GotoTop()
+}
+
+func (m *Model) updateProfilePickerMode(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+
+	names := m.config.ProfileNames()
+	switch keyMsg.String() {
+	case KeyUp:
+		if m.profileCursor > 0 {
+			m.profileCursor--
+		}
+	case KeyDown:
+		if m.profileCursor < len(names)-1 {
+			m.profileCursor++
+		}
+	case KeyEnter:
+		if err := m.selectProfile(names[m.profileCursor]); err != nil {
+			m.inputErrorMsg = "Error: " + err.Error()
+			return nil
+		}
+		m.mode = modeNormal
+		return m.refreshSelectionStats()
+	case KeyEscape, KeyCtrlC:
+		m.mode = modeNormal
+		m.inputErrorMsg = ""
+	}
+	return nil
+}
+
+// selectProfile switches to a profile: its patterns, format and output file
+// replace the current ones, and its files replace the selection. The
+// configuration is shared with the build and the stats job, so the job is
+// cancelled and waited for before it changes.
+func (m *Model) selectProfile(name string) error {
+	profileConfig, err := m.config.WithProfile(name, profileSource)
+	if err != nil {
+		return err
+	}
+	m.stopSelectionStats()
+	m.statsMu.Lock()
+	*m.config = *profileConfig
+	m.statsMu.Unlock()
+	m.filter = filter.New(m.fsys, m.config)
+
+	if err := m.selectProfilePaths(); err != nil {
+		return err
+	}
+	m.changeDirectory(m.path)
+	return nil
+}
+
+// selectProfilePaths pre-populates the selection with every file the active
+// profile would build: its paths, or the whole project if it lists none.
+func (m *Model) selectProfilePaths() error {
+	paths := m.config.ProfilePaths()
+	if len(paths) == 0 {
+		paths = []string{m.config.Exclude.Base}
+	}
+
+	files, _, err := fs.DiscoverFiles(m.fsys, paths, m.filter)
+	if err != nil {
+		return fmt.Errorf("could not list the files of profile %q: %w", m.config.Profile, err)
+	}
+
+	m.Select(files)
+	return nil
+}
+
+func (m *Model) renderProfileHeader() string {
+	header := ProfileHeader
+	if m.inputErrorMsg != "" {
+		header += Styles.Log.Error.Render(m.inputErrorMsg) + "\n"
+	}
+	return header
+}
+
+func (m *Model) renderProfileList() string {
+	var s strings.Builder
+	for i, name := range m.config.ProfileNames() {
+		profile := m.config.Profiles[name]
+
+		cursorStr := Elements.List.CursorEmpty
+		style := Styles.List.Normal
+		if i == m.profileCursor {
+			cursorStr = Icons.Cursor
+			style = style.Bold(true)
+		}
+		if name == m.config.Profile {
+			style = Styles.List.Selected
+		}
+
+		line := fmt.Sprintf("%s %s", cursorStr, name)
+		if profile.Description != "" {
+			line += Styles.List.Hint.Render(" - " + profile.Description)
+		}
+		s.WriteString(style.Render(line) + "\n")
+	}
+	return s.String()
+}
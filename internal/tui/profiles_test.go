@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/kacperzielinskidev/getctx/internal/build"
+	"github.com/kacperzielinskidev/getctx/internal/config"
+	"github.com/kacperzielinskidev/getctx/internal/fs"
+	"github.com/kacperzielinskidev/getctx/internal/logger"
+	"github.com/kacperzielinskidev/getctx/internal/tokens"
+)
+
+// TestSelectProfileWhileStatsRun switches profiles while the footer stats
+// are being measured; run it with -race to check that the job never reads
+// the configuration while it changes.
+func TestSelectProfileWhileStatsRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	files := make(map[string]string)
+	for i := range 200 {
+		files[fmt.Sprintf("pkg%d/file%d.go", i%10, i)] = "package pkg\n"
+	}
+	writeTree(t, root, files)
+
+	fsys := fs.NewOSFileSystem()
+	cfg := testConfig(t, fsys, root, root, nil)
+	cfg.Profiles["go"] = config.Profile{Include: []string{"*.go"}}
+	cfg.Profiles["pkg0"] = config.Profile{Paths: []string{filepath.Join(root, "pkg0")}}
+	builder := build.NewContextBuilder(logger.New(io.Discard, logger.LevelInfo), fsys, cfg, tokens.NewHeuristicCounter())
+
+	m, err := NewModel(root, cfg, fsys, builder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Select([]string{root})
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		cmd := m.refreshSelectionStats()
+		if cmd == nil {
+			t.Fatal("no stats job was started")
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd()
+		}()
+
+		name := "go"
+		if i%2 == 1 {
+			name = "pkg0"
+		}
+		if err := m.selectProfile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kacperzielinskidev/getctx/internal/session"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxStaleListed caps how many pruned paths the restore prompt lists.
+const maxStaleListed = 10
+
+// pendingSession is a saved session waiting for the user to restore or
+// discard it.
+type pendingSession struct {
+	savedAt  string
+	path     string
+	cursor   int
+	selected []string
+	stale    []string
+}
+
+// OfferSession asks, before anything else, whether to restore a saved
+// session. Selected paths that no longer exist are pruned and listed.
+func (m *Model) OfferSession(saved *session.Session) {
+	path, selected, stale := saved.Resolve(m.fsys)
+	if len(selected) == 0 && len(stale) == 0 {
+		return
+	}
+
+	m.pending = &pendingSession{
+		savedAt:  saved.SavedAt.Local().Format("2006-01-02 15:04"),
+		path:     path,
+		cursor:   saved.Cursor,
+		selected: selected,
+		stale:    stale,
+	}
+	m.mode = modeRestorePrompt
+}
+
+// Session captures the state to save on exit, or nil while a saved session
+// is still being offered, so quitting from the prompt keeps the old one.
+func (m *Model) Session() *session.Session {
+	if m.pending != nil {
+		return nil
+	}
+	return session.New(m.config.Exclude.Base, m.path, m.cursor, m.GetSelectedPaths())
+}
+
+func (m *Model) updateRestorePromptMode(msg tea.Msg) tea.Cmd {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+
+	switch strings.ToLower(keyMsg.String()) {
+	case "y", KeyEnter:
+		pending := m.pending
+		m.pending = nil
+		m.mode = modeNormal
+		m.Select(pending.selected)
+		if pending.path != m.path {
+			m.changeDirectory(pending.path)
+		}
+		m.cursor = pending.cursor
+		m.clampCursor()
+		return m.refreshSelectionStats()
+	case "n", KeyEscape:
+		m.pending = nil
+		m.mode = modeNormal
+	case KeyCtrlC:
+		m.Aborted = true
+		return tea.Quit
+	}
+	return nil
+}
+
+func (m *Model) renderRestorePrompt() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "Saved %s: %d selected item(s) in %s\n", m.pending.savedAt, len(m.pending.selected), m.pending.path)
+
+	if len(m.pending.stale) > 0 {
+		s.WriteString("\n")
+		s.WriteString(Styles.Log.Error.Render(fmt.Sprintf(StaleSessionFormat, len(m.pending.stale))))
+		s.WriteString("\n")
+		for i, path := range m.pending.stale {
+			if i == maxStaleListed {
+				fmt.Fprintf(&s, "  ... and %d more\n", len(m.pending.stale)-maxStaleListed)
+				break
+			}
+			fmt.Fprintf(&s, "  - %s\n", path)
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kacperzielinskidev/getctx/internal/build"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type selectionStatsMsg struct {
+	generation int
+	stats      build.Measurement
+}
+
+// refreshSelectionStats starts a background walk of the current selection
+// that applies the rules of the build, so the numbers match what pressing
+// 'q' would produce. Every call bumps the generation and cancels the
+// previous walk, so results that arrive late for an outdated selection are
+// ignored.
+func (m *Model) refreshSelectionStats() tea.Cmd {
+	m.stopSelectionStats()
+	m.statsGeneration++
+
+	paths := m.GetSelectedPaths()
+	if len(paths) == 0 {
+		m.selectionStats = build.Measurement{}
+		m.statsPending = false
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelStats = cancel
+	m.statsPending = true
+
+	generation := m.statsGeneration
+	builder := m.builder
+	statsMu := m.statsMu
+
+	return func() tea.Msg {
+		statsMu.RLock()
+		defer statsMu.RUnlock()
+		// The configuration may have changed between cancelling the job
+		// and this point; a cancelled job must not read it.
+		if ctx.Err() != nil {
+			return nil
+		}
+		stats, err := builder.Measure(ctx, paths)
+		if err != nil {
+			return nil
+		}
+		return selectionStatsMsg{generation: generation, stats: stats}
+	}
+}
+
+func (m *Model) stopSelectionStats() {
+	if m.cancelStats != nil {
+		m.cancelStats()
+		m.cancelStats = nil
+	}
+}
+
+func (m *Model) handleSelectionStats(msg selectionStatsMsg) {
+	if msg.generation != m.statsGeneration {
+		return
+	}
+	m.selectionStats = msg.stats
+	m.statsPending = false
+	m.cancelStats = nil
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
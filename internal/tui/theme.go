@@ -3,6 +3,8 @@ package tui
 import (
 	"fmt"
 
+	"github.com/kacperzielinskidev/getctx/internal/config"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -58,12 +60,19 @@ var Icons TUIIcons
 var Elements TUIElements
 var Colors TUIColors
 var Styles TUIStyles
-var HelpHeader string
 var InputHeader string
 var FilterHeader string
+var ProfileHeader string
+var RestoreHeader string
+var ExportHeader string
+var ImportHeader string
 var FilterIndicatorFormat string
+var ProfileIndicatorFormat string
 var PathPrefix string
 var StatusFooterFormat string
+var StatsFormat string
+var StatsPendingMessage string
+var StaleSessionFormat string
 var EmptyMessage string
 var NoMatchesMessage string
 
@@ -76,9 +85,48 @@ func init() {
 		Excluded:  "🚫",
 	}
 
+	Elements = TUIElements{
+		List: TUIListElements{
+			CursorEmpty:      " ",
+			SelectedPrefix:   Icons.Checkmark + " ",
+			UnselectedPrefix: "  ",
+			DirectorySuffix:  "/",
+		},
+	}
+
+	FilterIndicatorFormat = " [Filtering by: \"%s\"]"
+	ProfileIndicatorFormat = " [Profile: %s]"
+	PathPrefix = "Current path: "
+	StatusFooterFormat = "\nSelected %d items%s. Press '%s' to save and exit."
+	StatsFormat = " (%d files, %s, ~%d tokens)"
+	StatsPendingMessage = " (calculating...)"
+	StaleSessionFormat = "%d path(s) no longer exist and were dropped:"
+	EmptyMessage = "[ This directory is empty ]"
+	NoMatchesMessage = "[ No matching files or directories found ]"
+
+	applyColors(colorGreen, colorCyan, colorRed)
+}
+
+// ApplyTheme replaces the built-in colors with the configured ones.
+func ApplyTheme(theme config.Theme) {
+	selected, hint, errorColor := colorGreen, colorCyan, colorRed
+	if theme.Selected != "" {
+		selected = lipgloss.Color(theme.Selected)
+	}
+	if theme.Hint != "" {
+		hint = lipgloss.Color(theme.Hint)
+	}
+	if theme.Error != "" {
+		errorColor = lipgloss.Color(theme.Error)
+	}
+	applyColors(selected, hint, errorColor)
+}
+
+// applyColors rebuilds the styles and the headers that use them.
+func applyColors(selected, hint, errorColor lipgloss.Color) {
 	Colors = TUIColors{
-		Green: colorGreen,
-		Red:   colorRed,
+		Green: selected,
+		Red:   errorColor,
 	}
 
 	Styles = TUIStyles{
@@ -87,7 +135,7 @@ func init() {
 			Cursor:   lipgloss.NewStyle().Bold(true),
 			Excluded: lipgloss.NewStyle().Faint(true),
 			Normal:   lipgloss.NewStyle(),
-			Hint:     lipgloss.NewStyle().Foreground(colorCyan),
+			Hint:     lipgloss.NewStyle().Foreground(hint),
 			Empty:    lipgloss.NewStyle().Faint(true),
 		},
 		Log: TUILogStyles{
@@ -95,20 +143,6 @@ func init() {
 		},
 	}
 
-	Elements = TUIElements{
-		List: TUIListElements{
-			CursorEmpty:      " ",
-			SelectedPrefix:   Icons.Checkmark + " ",
-			UnselectedPrefix: "  ",
-			DirectorySuffix:  "/",
-		},
-	}
-
-	HelpHeader = lipgloss.JoinHorizontal(lipgloss.Left,
-		"Select files ",
-		Styles.List.Hint.Render("(space: select, a: select all, ctrl+p: find path, /: filter, esc: clear filters, q: save, ctrl+c: quit)"),
-	) + "\n"
-
 	InputHeader = lipgloss.JoinHorizontal(lipgloss.Left,
 		"Enter path ",
 		Styles.List.Hint.Render("(enter: confirm, esc: cancel, tab: autocomplete)"),
@@ -119,11 +153,32 @@ func init() {
 		Styles.List.Hint.Render("(type to filter, enter: confirm, esc: cancel)"),
 	) + "\n"
 
-	FilterIndicatorFormat = " [Filtering by: \"%s\"]"
-	PathPrefix = "Current path: "
-	StatusFooterFormat = "\nSelected %d items. Press 'q' to save and exit."
-	EmptyMessage = "[ This directory is empty ]"
-	NoMatchesMessage = "[ No matching files or directories found ]"
+	ExportHeader = lipgloss.JoinHorizontal(lipgloss.Left,
+		"Export the selection to ",
+		Styles.List.Hint.Render("(.json for JSON, a plain path list otherwise; enter: confirm, esc: cancel)"),
+	) + "\n"
+
+	ImportHeader = lipgloss.JoinHorizontal(lipgloss.Left,
+		"Import a selection from ",
+		Styles.List.Hint.Render("(replaces the current selection; enter: confirm, esc: cancel)"),
+	) + "\n"
+
+	RestoreHeader = lipgloss.JoinHorizontal(lipgloss.Left,
+		"Restore the previous session? ",
+		Styles.List.Hint.Render("(y: restore, n: start fresh)"),
+	) + "\n"
+
+	ProfileHeader = lipgloss.JoinHorizontal(lipgloss.Left,
+		"Choose a profile ",
+		Styles.List.Hint.Render("(enter: select its files, esc: cancel)"),
+	) + "\n"
+}
+
+func renderHelpHeader(keys keyMap) string {
+	return lipgloss.JoinHorizontal(lipgloss.Left,
+		"Select files ",
+		Styles.List.Hint.Render("("+keys.help()+")"),
+	) + "\n"
 }
 
 func formatFilterIndicator(query string) string {
@@ -134,11 +189,31 @@ func formatFilterIndicator(query string) string {
 	return Styles.List.Hint.Render(indicator)
 }
 
+func formatProfileIndicator(profile string) string {
+	if profile == "" {
+		return ""
+	}
+	return Styles.List.Hint.Render(fmt.Sprintf(ProfileIndicatorFormat, profile))
+}
+
 func (m *Model) ensureCursorVisible() {
-	if m.cursor < m.viewport.YOffset {
-		m.viewport.SetYOffset(m.cursor)
+	// Before the first window size message there is no room to scroll in.
+	if m.viewport.Height <= 0 {
+		return
+	}
+
+	cursor := m.cursor
+	switch m.mode {
+	case modeProfilePicker:
+		cursor = m.profileCursor
+	case modeRestorePrompt:
+		cursor = 0
+	}
+
+	if cursor < m.viewport.YOffset {
+		m.viewport.SetYOffset(cursor)
 	}
-	if m.cursor >= m.viewport.YOffset+m.viewport.Height {
-		m.viewport.SetYOffset(m.cursor - m.viewport.Height + 1)
+	if cursor >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(cursor - m.viewport.Height + 1)
 	}
 }
@@ -18,6 +18,9 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.textInput.Width = max(m.width-len(m.textInput.Prompt)-1, 1)
+	case selectionStatsMsg:
+		m.handleSelectionStats(msg)
+		return m, nil
 	}
 
 	switch m.mode {
@@ -27,6 +30,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmd = m.updatePathInputMode(msg)
 	case modeFilter:
 		cmd = m.updateFilterMode(msg)
+	case modeProfilePicker:
+		cmd = m.updateProfilePickerMode(msg)
+	case modeRestorePrompt:
+		cmd = m.updateRestorePromptMode(msg)
+	case modeExportInput, modeImportInput:
+		cmd = m.updateListInputMode(msg)
 	}
 	cmds = append(cmds, cmd)
 
@@ -47,33 +56,44 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *Model) updateNormalMode(msg tea.Msg) tea.Cmd {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case KeyUp:
+		m.notice = ""
+		switch m.keys.action(msg.String()) {
+		case actionUp:
 			m.handleMoveCursorUp()
-		case KeyDown:
+		case actionDown:
 			m.handleMoveCursorDown()
-		case KeyCtrlHome:
+		case actionTop:
 			m.handleGoToTop()
-		case KeyCtrlEnd:
+		case actionBottom:
 			m.handleGoToBottom()
-		case KeyEnter:
+		case actionOpen:
 			m.enterDirectory()
-		case KeyBackspace:
+		case actionParent:
 			m.navigateToParent()
-		case KeySpace:
+		case actionSelect:
 			m.toggleSelection()
-		case KeyCtrlA:
+			return m.refreshSelectionStats()
+		case actionSelectAll:
 			m.toggleSelectAll()
-		case KeySlash:
+			return m.refreshSelectionStats()
+		case actionFilter:
 			return m.enterFilterMode()
-		case KeyP:
+		case actionFindPath:
 			return m.enterPathInputMode()
-		case KeyEscape:
+		case actionClearFilter:
 			m.clearFilter()
-		case KeyQ:
+		case actionProfiles:
+			m.enterProfilePickerMode()
+		case actionExport:
+			return m.enterListInputMode(modeExportInput)
+		case actionImport:
+			return m.enterListInputMode(modeImportInput)
+		case actionSave:
+			m.stopSelectionStats()
 			return tea.Quit
-		case KeyCtrlC:
+		case actionQuit:
 			m.Aborted = true
+			m.stopSelectionStats()
 			return tea.Quit
 		}
 	}
@@ -225,8 +245,24 @@ func (m *Model) toggleSelectAll() {
 }
 
 func (m *Model) confirmPathChange() {
-	inputPath := m.textInput.Value()
+	cleanedPath := m.resolveInputPath(m.textInput.Value())
+	info, err := m.fsys.Stat(cleanedPath)
+	if err != nil {
+		m.inputErrorMsg = "Error: Path not found or is inaccessible."
+		return
+	}
+	if !info.IsDir() {
+		m.inputErrorMsg = "Error: Path is a file, not a directory."
+		return
+	}
+
+	m.changeDirectory(cleanedPath)
+	m.cancelInputMode()
+}
 
+// resolveInputPath expands a leading ~ and makes a typed path absolute,
+// relative to the current directory.
+func (m *Model) resolveInputPath(inputPath string) string {
 	if strings.HasPrefix(inputPath, "~") {
 		home, err := m.fsys.UserHomeDir()
 		if err == nil {
@@ -242,20 +278,7 @@ func (m *Model) confirmPathChange() {
 	} else {
 		finalPath = filepath.Join(m.path, inputPath)
 	}
-
-	cleanedPath := filepath.Clean(finalPath)
-	info, err := m.fsys.Stat(cleanedPath)
-	if err != nil {
-		m.inputErrorMsg = "Error: Path not found or is inaccessible."
-		return
-	}
-	if !info.IsDir() {
-		m.inputErrorMsg = "Error: Path is a file, not a directory."
-		return
-	}
-
-	m.changeDirectory(cleanedPath)
-	m.cancelInputMode()
+	return filepath.Clean(finalPath)
 }
 
 func (m *Model) enterPathInputMode() tea.Cmd {
@@ -17,6 +17,12 @@ func (m *Model) View() string {
 	switch m.mode {
 	case modePathInput:
 		mainContent = m.renderCompletionView()
+	case modeProfilePicker:
+		m.viewport.SetContent(m.renderProfileList())
+		mainContent = m.viewport.View()
+	case modeRestorePrompt:
+		m.viewport.SetContent(m.renderRestorePrompt())
+		mainContent = m.viewport.View()
 	default:
 		m.viewport.SetContent(m.renderFileListView())
 		mainContent = m.viewport.View()
@@ -45,17 +51,24 @@ func (m *Model) getVisibleItems() []listItem {
 }
 
 func (m *Model) renderHeader() string {
-	if m.mode == modePathInput || m.mode == modeFilter {
+	if m.mode == modePathInput || m.mode == modeFilter || m.mode == modeExportInput || m.mode == modeImportInput {
 		return m.renderTextInput()
 	}
+	if m.mode == modeProfilePicker {
+		return m.renderProfileHeader()
+	}
+	if m.mode == modeRestorePrompt {
+		return RestoreHeader
+	}
 
 	filterIndicator := formatFilterIndicator(m.filterQuery)
+	profileIndicator := formatProfileIndicator(m.config.Profile)
 	pathStyle := lipgloss.NewStyle().Width(m.width)
-	fullPathString := PathPrefix + m.path + filterIndicator
+	fullPathString := PathPrefix + m.path + profileIndicator + filterIndicator
 	wrappedPath := pathStyle.Render(fullPathString)
 
 	return lipgloss.JoinVertical(lipgloss.Left,
-		HelpHeader,
+		m.helpHeader,
 		wrappedPath,
 	)
 }
@@ -64,8 +77,13 @@ func (m *Model) renderTextInput() string {
 	var s strings.Builder
 
 	prompt := InputHeader
-	if m.mode == modeFilter {
+	switch m.mode {
+	case modeFilter:
 		prompt = FilterHeader
+	case modeExportInput:
+		prompt = ExportHeader
+	case modeImportInput:
+		prompt = ImportHeader
 	}
 
 	s.WriteString(prompt)
@@ -79,7 +97,21 @@ func (m *Model) renderTextInput() string {
 }
 
 func (m *Model) renderFooter() string {
-	return fmt.Sprintf(StatusFooterFormat, len(m.selected))
+	var stats string
+	switch {
+	case len(m.selected) == 0:
+		stats = ""
+	case m.statsPending:
+		stats = Styles.List.Hint.Render(StatsPendingMessage)
+	default:
+		stats = Styles.List.Hint.Render(fmt.Sprintf(StatsFormat,
+			m.selectionStats.Files, formatBytes(m.selectionStats.Bytes), m.selectionStats.Tokens))
+	}
+	footer := fmt.Sprintf(StatusFooterFormat, len(m.selected), stats, m.keys.saveKey())
+	if m.notice != "" {
+		footer += "\n" + Styles.List.Hint.Render(m.notice)
+	}
+	return footer
 }
 
 func (m *Model) renderFileListView() string {